@@ -0,0 +1,132 @@
+package pipelines
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// defaultAutoscaleInterval is the interval used to evaluate the autoscaling policies if it is not set in the pipeline configuration.
+const defaultAutoscaleInterval = time.Second
+
+// autoscaleTolerance is the ratio around the target utilization in which the replicas are not changed to avoid flapping.
+const autoscaleTolerance = 0.1
+
+// AutoscalePolicy is the policy used to scale the replicas of a step based on the utilization of its input channel.
+type AutoscalePolicy struct {
+
+	// MinReplicas is the min number of replicas of the step. It is set to 1 if it is not set.
+	MinReplicas uint16
+
+	// MaxReplicas is the max number of replicas of the step.
+	MaxReplicas uint16
+
+	// TargetUtilization is the targeted ratio of the occupied input channel buffer between 0 and 1.
+	TargetUtilization float64
+
+	// Cooldown is the min period between two consecutive scaling of the step.
+	Cooldown time.Duration
+}
+
+// autoscaled is implemented by the steps which have an autoscaling policy.
+type autoscaled interface {
+	GetAutoscalePolicy() *AutoscalePolicy
+}
+
+func (s *stepBase[I]) GetAutoscalePolicy() *AutoscalePolicy {
+	return s.autoscale
+}
+
+// setAutoscalePolicy validates and sets the autoscaling policy of the step, and clamps the replicas to the policy limits.
+func (s *stepBase[I]) setAutoscalePolicy(policy *AutoscalePolicy) {
+	if policy == nil {
+		return
+	}
+	validated := *policy
+	if validated.MinReplicas == 0 {
+		validated.MinReplicas = 1
+	}
+	if validated.MaxReplicas < validated.MinReplicas {
+		panic("autoscale max replicas must be greater than or equal to min replicas")
+	}
+	if validated.TargetUtilization <= 0 || validated.TargetUtilization > 1 {
+		panic("autoscale target utilization must be greater than 0 and less than or equal to 1")
+	}
+	s.replicas = min(max(s.replicas, validated.MinReplicas), validated.MaxReplicas)
+	s.autoscale = &validated
+}
+
+// desiredReplicas calculates the replicas needed to reach the target utilization.
+func desiredReplicas(policy AutoscalePolicy, current uint16, utilization float64) uint16 {
+	ratio := utilization / policy.TargetUtilization
+	if math.Abs(ratio-1) <= autoscaleTolerance {
+		return current
+	}
+	desired := math.Ceil(float64(current) * ratio)
+	return uint16(min(max(desired, float64(policy.MinReplicas)), float64(policy.MaxReplicas)))
+}
+
+// autoscaleState is the state of the autoscaling of a single step.
+type autoscaleState struct {
+	index      int
+	policy     AutoscalePolicy
+	lastScaled time.Time
+}
+
+// autoscaleStates returns the states of the steps which have an autoscaling policy.
+func (p *pipeline[I]) autoscaleStates() []*autoscaleState {
+	var states []*autoscaleState
+	for i, step := range p.steps {
+		if s, ok := step.(autoscaled); ok && s.GetAutoscalePolicy() != nil {
+			states = append(states, &autoscaleState{index: i, policy: *s.GetAutoscalePolicy()})
+		}
+	}
+	return states
+}
+
+// runAutoscaler evaluates the autoscaling policies periodically till the context is cancelled.
+func (p *pipeline[I]) runAutoscaler(ctx context.Context, wg *sync.WaitGroup, states []*autoscaleState) {
+	defer wg.Done()
+
+	interval := p.autoscaleInterval
+	if interval == 0 {
+		interval = defaultAutoscaleInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, state := range states {
+				p.evaluateAutoscale(state, now)
+			}
+		}
+	}
+}
+
+// evaluateAutoscale scales the step if its utilization is off the target and the cooldown period has passed.
+func (p *pipeline[I]) evaluateAutoscale(state *autoscaleState, now time.Time) {
+	if now.Sub(state.lastScaled) < state.policy.Cooldown {
+		return
+	}
+
+	length, capacity := p.queueDepth(state.index)
+	if capacity == 0 {
+		return
+	}
+	utilization := float64(length) / float64(capacity)
+
+	current := p.runningReplicas(state.index)
+	desired := desiredReplicas(state.policy, current, utilization)
+	if desired == current {
+		return
+	}
+	if err := p.scaleStep(state.index, desired); err != nil {
+		return
+	}
+	state.lastScaled = now
+}
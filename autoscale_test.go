@@ -0,0 +1,115 @@
+package pipelines
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAutoscale_DesiredReplicas(t *testing.T) {
+	policy := AutoscalePolicy{MinReplicas: 1, MaxReplicas: 10, TargetUtilization: 0.5}
+
+	tests := []struct {
+		name        string
+		current     uint16
+		utilization float64
+		expected    uint16
+	}{
+		{"OnTarget", 2, 0.5, 2},
+		{"WithinTolerance", 2, 0.52, 2},
+		{"ScaleUp", 2, 1, 4},
+		{"ScaleDown", 4, 0.25, 2},
+		{"Idle", 4, 0, 1},
+		{"MaxReplicas", 8, 1, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if desired := desiredReplicas(policy, tt.current, tt.utilization); desired != tt.expected {
+				t.Errorf("expected %d replicas, got %d", tt.expected, desired)
+			}
+		})
+	}
+}
+
+func TestAutoscale_SetPolicy(t *testing.T) {
+	step := newBaseStep[int]("step", 20, 0)
+	step.setAutoscalePolicy(&AutoscalePolicy{MaxReplicas: 5, TargetUtilization: 0.5})
+
+	if step.GetAutoscalePolicy().MinReplicas != 1 {
+		t.Errorf("expected min replicas to default to 1, got %d", step.GetAutoscalePolicy().MinReplicas)
+	}
+	if step.replicas != 5 {
+		t.Errorf("expected replicas to be clamped to 5, got %d", step.replicas)
+	}
+
+	invalid := []AutoscalePolicy{
+		{MinReplicas: 5, MaxReplicas: 2, TargetUtilization: 0.5},
+		{MaxReplicas: 2},
+		{MaxReplicas: 2, TargetUtilization: 1.5},
+	}
+	for _, policy := range invalid {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("expected policy %+v to panic", policy)
+				}
+			}()
+			step.setAutoscalePolicy(&policy)
+		}()
+	}
+}
+
+func TestPipeline_Autoscale(t *testing.T) {
+	release := make(chan struct{})
+	builder := &Builder[int]{}
+	work := builder.NewStep(StepBasicConfig[int]{
+		Label:            "work",
+		InputChannelSize: 4,
+		Process: func(i int) int {
+			<-release
+			return i
+		},
+		StepOptions: StepOptions[int]{
+			Autoscale: &AutoscalePolicy{MinReplicas: 1, MaxReplicas: 4, TargetUtilization: 0.5, Cooldown: time.Minute},
+		},
+	})
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(int) {}})
+	p := builder.NewPipeline(PipelineConfig{
+		DefaultStepInputChannelSize: 10,
+		TrackTokensCount:            true,
+		AutoscaleInterval:           time.Hour,
+	}, work, sink).(*pipeline[int])
+	p.Init()
+	p.Run(context.Background())
+
+	// one token is held by the replica and the rest fill the input channel.
+	p.FeedMany([]int{1, 2, 3, 4, 5})
+	for len(work.GetInputChannel()) < 4 {
+		time.Sleep(time.Millisecond)
+	}
+
+	states := p.autoscaleStates()
+	if len(states) != 1 {
+		t.Fatalf("expected 1 autoscaled step, got %d", len(states))
+	}
+	now := time.Now()
+	p.evaluateAutoscale(states[0], now)
+	if p.runningReplicas(0) != 2 {
+		t.Errorf("expected step to scale up to 2 replicas, got %d", p.runningReplicas(0))
+	}
+
+	// cooldown prevents scaling again.
+	p.evaluateAutoscale(states[0], now.Add(time.Second))
+	if p.runningReplicas(0) != 2 {
+		t.Errorf("expected replicas not to change during cooldown, got %d", p.runningReplicas(0))
+	}
+
+	close(release)
+	p.WaitTillDone()
+
+	p.evaluateAutoscale(states[0], now.Add(2*time.Minute))
+	if p.runningReplicas(0) != 1 {
+		t.Errorf("expected idle step to scale down to 1 replica, got %d", p.runningReplicas(0))
+	}
+	p.Terminate()
+}
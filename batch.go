@@ -0,0 +1,120 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"slices"
+	"time"
+)
+
+// drainPollInterval is the interval at which the tokens count is checked while draining the pipeline at the end of the input.
+const drainPollInterval = time.Millisecond
+
+func (p *pipeline[I]) RunAll(ctx context.Context, inputs []I) ([]I, error) {
+	return p.RunSeq(ctx, slices.Values(inputs))
+}
+
+func (p *pipeline[I]) RunSeq(ctx context.Context, inputs iter.Seq[I]) ([]I, error) {
+	if !p.trackTokensCount {
+		return nil, ErrTokensCountNotTracked
+	}
+	if p.State() == StateCreated {
+		if err := p.Init(); err != nil {
+			return nil, err
+		}
+	}
+
+	errs := []error{}
+	p.errorsMutex.Lock()
+	p.collectedErrors = &errs
+	p.errorsMutex.Unlock()
+	defer func() {
+		p.errorsMutex.Lock()
+		p.collectedErrors = nil
+		p.errorsMutex.Unlock()
+	}()
+
+	if err := p.Run(ctx); err != nil {
+		return nil, err
+	}
+
+	// the outputs are received while feeding, since the tokens are done only once they are received.
+	var outputs []I
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for token := range p.OutputSeq() {
+			outputs = append(outputs, token)
+		}
+	}()
+
+	err := p.runInputs(ctx, inputs)
+	terminateErr := p.Terminate()
+	<-collected
+
+	p.errorsMutex.Lock()
+	defer p.errorsMutex.Unlock()
+	// the feed errors other than cancellation are already reported.
+	if ctx.Err() != nil {
+		errs = append(errs, err)
+	}
+	return outputs, errors.Join(append(errs, terminateErr)...)
+}
+
+// runInputs feeds the inputs and waits till the pipeline is drained, flushing the buffer steps from the first to the last.
+func (p *pipeline[I]) runInputs(ctx context.Context, inputs iter.Seq[I]) error {
+	for item := range inputs {
+		if err := p.feed(ctx, item, nil); err != nil {
+			return err
+		}
+	}
+	for _, step := range p.steps {
+		buffered, ok := step.(bufferedStep)
+		if !ok {
+			continue
+		}
+		if err := p.waitTillRetained(ctx); err != nil {
+			return err
+		}
+		buffered.flush()
+	}
+	return p.waitTillRetained(ctx)
+}
+
+// waitTillRetained blocks till the only tokens left in the pipeline are the ones retained by the buffer steps.
+// It polls the tokens count since retaining a token in a buffer doesn't change it.
+func (p *pipeline[I]) waitTillRetained(ctx context.Context) error {
+	var buffers []bufferedStep
+	for _, step := range p.steps {
+		if buffered, ok := step.(bufferedStep); ok {
+			buffers = append(buffers, buffered)
+		}
+	}
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		if p.retainedOnly(buffers, 0) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// retainedOnly checks if the tokens count is the number of tokens retained by the buffers.
+// The buffers are locked from the first to the last while checking, since the buffer steps change the tokens count
+// while they are locked, and they wait only for the following steps while they are locked.
+func (p *pipeline[I]) retainedOnly(buffers []bufferedStep, retained int) bool {
+	if len(buffers) == 0 {
+		return p.TokensCount() <= uint64(retained)
+	}
+	var result bool
+	buffers[0].locked(func(count int) {
+		result = p.retainedOnly(buffers[1:], retained+count)
+	})
+	return result
+}
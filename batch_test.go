@@ -0,0 +1,153 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+)
+
+// failingStorage is a checkpoint storage failing to save.
+type failingStorage struct{}
+
+var errStorageFailed = errors.New("storage failed")
+
+func (failingStorage) Save(string, []byte) error {
+	return errStorageFailed
+}
+
+func (failingStorage) Load(string) ([]byte, error) {
+	return nil, ErrCheckpointNotFound
+}
+
+func TestPipeline_RunAll(t *testing.T) {
+	builder := &Builder[int]{}
+	double := builder.NewStep(StepBasicConfig[int]{Label: "double", Process: func(i int) int { return i * 2 }})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 2, TrackTokensCount: true}, double)
+
+	outputs, err := p.RunAll(context.Background(), []int{1, 2, 3, 4, 5})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sort.Ints(outputs)
+	if !slices.Equal(outputs, []int{2, 4, 6, 8, 10}) {
+		t.Errorf("unexpected outputs: %v", outputs)
+	}
+	if p.State() != StateTerminated {
+		t.Errorf("expected state to be terminated, got %s", p.State())
+	}
+}
+
+func TestPipeline_RunSeq_FlushBuffers(t *testing.T) {
+	builder := &Builder[int]{}
+	// the first buffer sums every 3 tokens, and the second sums all of its tokens only when it is flushed.
+	triplets := builder.NewStep(StepBufferConfig[int]{
+		Label:      "triplets",
+		BufferSize: 3,
+		InputTriggeredProcess: func(buffer []int) (int, BufferFlags) {
+			if len(buffer) < 3 {
+				return 0, BufferFlags{}
+			}
+			return buffer[0] + buffer[1] + buffer[2], BufferFlags{SendProcessOuput: true, FlushBuffer: true}
+		},
+	})
+	total := builder.NewStep(StepBufferConfig[int]{
+		Label:                        "total",
+		BufferSize:                   10,
+		TimeTriggeredProcessInterval: time.Hour,
+		TimeTriggeredProcess: func(buffer []int) (int, BufferFlags) {
+			sum := 0
+			for _, i := range buffer {
+				sum += i
+			}
+			return sum, BufferFlags{SendProcessOuput: true, FlushBuffer: true}
+		},
+	})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, triplets, total)
+
+	// the remaining 7 is dropped by the first buffer since its process sends nothing for it.
+	outputs, err := p.RunSeq(context.Background(), slices.Values([]int{1, 2, 3, 4, 5, 6, 7}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(outputs, []int{21}) {
+		t.Errorf("expected [21], got %v", outputs)
+	}
+}
+
+func TestPipeline_RunAll_InputTriggeredOnly(t *testing.T) {
+	builder := &Builder[int]{}
+	scaled := builder.NewStep(StepBufferConfig[int]{
+		Label:      "scaled",
+		BufferSize: 5,
+		InputTriggeredProcess: func(buffer []int) (int, BufferFlags) {
+			return buffer[len(buffer)-1] * 10, BufferFlags{SendProcessOuput: true}
+		},
+	})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, scaled)
+
+	// the retained tokens are released at the end without running the input triggered process again.
+	outputs, err := p.RunAll(context.Background(), []int{1, 2, 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(outputs, []int{10, 20, 30}) {
+		t.Errorf("expected [10 20 30], got %v", outputs)
+	}
+	if p.TokensCount() != 0 {
+		t.Errorf("expected tokens count to be 0, got %d", p.TokensCount())
+	}
+}
+
+func TestPipeline_RunAll_Errors(t *testing.T) {
+	builder := &Builder[int]{}
+	buffer := builder.NewStep(StepBufferConfig[int]{
+		Label:       "buffer",
+		BufferSize:  2,
+		PassThrough: true,
+		InputTriggeredProcess: func([]int) (int, BufferFlags) {
+			return 0, BufferFlags{}
+		},
+		Checkpoint: &CheckpointConfig[int]{Storage: failingStorage{}, Codec: NewGobCodec[int]()},
+	})
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(int) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, buffer, sink)
+
+	outputs, err := p.RunAll(context.Background(), []int{1, 2, 3})
+	if !errors.Is(err, errStorageFailed) {
+		t.Errorf("expected the checkpoint error, got %v", err)
+	}
+	if outputs != nil {
+		t.Errorf("expected no outputs, got %v", outputs)
+	}
+
+	untracked := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, sink)
+	if _, err := untracked.RunAll(context.Background(), []int{1}); !errors.Is(err, ErrTokensCountNotTracked) {
+		t.Errorf("expected ErrTokensCountNotTracked, got %v", err)
+	}
+}
+
+func TestPipeline_RunAll_Cancelled(t *testing.T) {
+	builder := &Builder[int]{}
+	release := make(chan struct{})
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(int) { <-release }})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 1, TrackTokensCount: true}, sink)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		_, err := p.RunAll(ctx, []int{1, 2, 3})
+		done <- err
+	}()
+	// the blocked process is released once the pipeline is being terminated.
+	for p.State() != StateDraining {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	if err := <-done; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
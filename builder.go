@@ -21,6 +21,10 @@ func (s *Builder[I]) NewStep(config StepConfig[I]) IStep[I] {
 		return newStepFilter(c)
 	case StepBufferConfig[I]:
 		return newStepBuffer(c)
+	case StepRateLimiterConfig[I]:
+		return newStepRateLimiter(c)
+	case StepWriterConfig[I]:
+		return newStepWriter(c)
 	default:
 		panic(fmt.Sprintf("unknown step configuration: %v", config))
 	}
@@ -33,9 +37,27 @@ func (s *Builder[I]) NewPipeline(config PipelineConfig, steps ...IStep[I]) IPipe
 		panic("DefaultStepChannelSize configuration is not set")
 	}
 
+	if config.Watchdog != nil && config.Watchdog.Interval <= 0 {
+		panic("watchdog interval must be greater than 0")
+	}
+
 	pipe := &pipeline[I]{}
 	pipe.steps = steps
 	pipe.trackTokensCount = config.TrackTokensCount
 	pipe.defaultChannelSize = config.DefaultStepInputChannelSize
+	pipe.errorHandler = config.ErrorHandler
+	pipe.watchdog = config.Watchdog
+	pipe.autoscaleInterval = config.AutoscaleInterval
+	pipe.lineages = newLineageRegistry()
+	return pipe
+}
+
+// NewPipelineWithWAL creates a new pipeline persisting the fed tokens in a write-ahead log till all the tokens derived
+// from them leave the pipeline. The tokens which are not acknowledged are fed again once the pipeline is run.
+// The pipeline has to be in envelope mode.
+func (s *Builder[I]) NewPipelineWithWAL(config PipelineConfig, wal WALConfig[I], steps ...IStep[I]) IPipeline[I] {
+	validateWALConfig(&wal)
+	pipe := s.NewPipeline(config, steps...).(*pipeline[I])
+	pipe.wal = newWriteAheadLog(wal)
 	return pipe
 }
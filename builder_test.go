@@ -1,6 +1,7 @@
 package pipelines
 
 import (
+	"bytes"
 	"testing"
 )
 
@@ -28,6 +29,13 @@ func TestBuilder_TestNewStep(t *testing.T) {
 			InputTriggeredProcess: func([]int) (int, BufferFlags) { return 0, BufferFlags{} },
 			BufferSize:            5,
 		}, false},
+		{"RateLimiterConfig", StepRateLimiterConfig[int]{
+			Rate: 10,
+		}, false},
+		{"WriterConfig", StepWriterConfig[int]{
+			Writer:  &bytes.Buffer{},
+			Encoder: EncoderFunc[int](func(int) ([]byte, error) { return nil, nil }),
+		}, false},
 	}
 
 	for _, tt := range tests {
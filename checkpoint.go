@@ -0,0 +1,185 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrCheckpointNotFound is returned by the checkpoint storage when no checkpoint is saved for the key.
+var ErrCheckpointNotFound = errors.New("checkpoint not found")
+
+// CheckpointStorage stores the checkpoints of the steps.
+type CheckpointStorage interface {
+
+	// Save replaces the checkpoint saved for the key.
+	Save(key string, data []byte) error
+
+	// Load returns the checkpoint saved for the key or ErrCheckpointNotFound if there is none.
+	Load(key string) ([]byte, error)
+}
+
+// CheckpointConfig is the configuration of checkpointing the state of a step.
+type CheckpointConfig[I any] struct {
+
+	// Storage is where the checkpoints are saved.
+	Storage CheckpointStorage
+
+	// Codec is used to encode the tokens retained by the step and decode them back.
+	Codec Codec[I]
+
+	// Key identifies the checkpoint of the step in the storage. The label of the step is used if it is not set.
+	Key string
+
+	// Interval is the period between saving checkpoints while the pipeline is running. If it is not set,
+	// the checkpoints are saved only when the pipeline is terminated or Checkpoint is called.
+	Interval time.Duration
+}
+
+// validateCheckpointConfig panics if the checkpoint configuration is not valid, and returns the key of the checkpoint.
+func validateCheckpointConfig[I any](config *CheckpointConfig[I], label string) string {
+	if config.Storage == nil {
+		panic("checkpoint storage is required")
+	}
+	if config.Codec == nil {
+		panic("checkpoint codec is required")
+	}
+	if config.Interval < 0 {
+		panic("checkpoint interval must not be negative")
+	}
+	if config.Key != "" {
+		return config.Key
+	}
+	if label == "" {
+		panic("checkpoint key or step label is required")
+	}
+	return label
+}
+
+// checkpointed is implemented by the steps whose state can be checkpointed.
+type checkpointed interface {
+
+	// saveCheckpoint saves the current state of the step.
+	saveCheckpoint() error
+
+	// restoreCheckpoint restores the state of the step from the last checkpoint and returns the number of restored tokens.
+	restoreCheckpoint() (int, error)
+
+	// checkpointInterval returns the period between saving checkpoints while running.
+	checkpointInterval() time.Duration
+}
+
+// fileCheckpointStorage stores every checkpoint in a file in a directory.
+type fileCheckpointStorage struct {
+	dir string
+}
+
+// NewFileCheckpointStorage creates a checkpoint storage keeping every checkpoint in a file in the given directory.
+// The directory is created if it doesn't exist, and the files are replaced atomically.
+func NewFileCheckpointStorage(dir string) CheckpointStorage {
+	if dir == "" {
+		panic("checkpoint directory is required")
+	}
+	return &fileCheckpointStorage{dir: dir}
+}
+
+func (s *fileCheckpointStorage) path(key string) string {
+	return filepath.Join(s.dir, url.PathEscape(key)+".checkpoint")
+}
+
+func (s *fileCheckpointStorage) Save(key string, data []byte) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(s.dir, ".checkpoint-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), s.path(key))
+}
+
+func (s *fileCheckpointStorage) Load(key string) ([]byte, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrCheckpointNotFound
+	}
+	return data, err
+}
+
+// restoreCheckpoints restores the state of the checkpointed steps, and counts the restored tokens.
+func (p *pipeline[I]) restoreCheckpoints() error {
+	restored := 0
+	for _, step := range p.steps {
+		if c, ok := step.(checkpointed); ok {
+			count, err := c.restoreCheckpoint()
+			if err != nil {
+				return fmt.Errorf("restoring checkpoint of step %q: %w", step.GetLabel(), err)
+			}
+			restored += count
+		}
+	}
+	if p.trackTokensCount {
+		p.tokensCountMutex.Lock()
+		p.tokensCount += uint64(restored)
+		p.tokensCountMutex.Unlock()
+	}
+	return nil
+}
+
+// saveCheckpoints saves the state of all the checkpointed steps, and reports the errors.
+func (p *pipeline[I]) saveCheckpoints() error {
+	var errs []error
+	for _, step := range p.steps {
+		if c, ok := step.(checkpointed); ok {
+			if err := c.saveCheckpoint(); err != nil {
+				err = fmt.Errorf("saving checkpoint: %w", err)
+				p.reportError(step.GetLabel(), err)
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runCheckpoints saves the checkpoint of the step periodically till the context is cancelled.
+func (p *pipeline[I]) runCheckpoints(ctx context.Context, wg *sync.WaitGroup, step IStep[I], interval time.Duration) {
+	defer wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := step.(checkpointed).saveCheckpoint(); err != nil {
+				p.reportError(step.GetLabel(), fmt.Errorf("saving checkpoint: %w", err))
+			}
+		}
+	}
+}
+
+func (p *pipeline[I]) Checkpoint() error {
+	if state := p.State(); state == StateCreated {
+		return &StateError{Op: "checkpoint", State: state}
+	}
+	return p.saveCheckpoints()
+}
@@ -0,0 +1,196 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileCheckpointStorage(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "checkpoints")
+	storage := NewFileCheckpointStorage(dir)
+
+	if _, err := storage.Load("moving/average"); !errors.Is(err, ErrCheckpointNotFound) {
+		t.Errorf("expected ErrCheckpointNotFound, got %v", err)
+	}
+
+	if err := storage.Save("moving/average", []byte("first")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := storage.Save("moving/average", []byte("second")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := storage.Load("moving/average")
+	if err != nil || string(data) != "second" {
+		t.Errorf("expected the last saved checkpoint, got %q %v", data, err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("expected a single checkpoint file without temporary files, got %d entries", len(entries))
+	}
+}
+
+func waitBuffered(t *testing.T, p IPipeline[int], buffered int) {
+	deadline := time.Now().Add(time.Second)
+	for p.Describe().Steps[0].Buffer.Buffered != buffered {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d buffered tokens, got %d", buffered, p.Describe().Steps[0].Buffer.Buffered)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPipeline_Checkpoint_RestoreOnInit(t *testing.T) {
+	dir := t.TempDir()
+	builder := &Builder[int]{}
+	// the buffer retains the last 5 tokens.
+	buffer := builder.NewStep(StepBufferConfig[int]{
+		Label:                 "window",
+		BufferSize:            5,
+		InputTriggeredProcess: func([]int) (int, BufferFlags) { return 0, BufferFlags{} },
+		Checkpoint:            &CheckpointConfig[int]{Storage: NewFileCheckpointStorage(dir), Codec: newIntCodec()},
+	})
+	sink := builder.NewStep(StepTerminalConfig[int]{Process: func(int) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, buffer, sink)
+	p.Init()
+	p.Run(context.Background())
+	p.FeedMany([]int{1, 2, 3, 4, 5, 6})
+	waitBuffered(t, p, 5)
+	p.Terminate()
+
+	// the buffer contents of the restarted pipeline are captured on every input.
+	var captured []int
+	var mutex sync.Mutex
+	buffer = builder.NewStep(StepBufferConfig[int]{
+		Label:      "window",
+		BufferSize: 5,
+		InputTriggeredProcess: func(buffer []int) (int, BufferFlags) {
+			mutex.Lock()
+			captured = append([]int(nil), buffer...)
+			mutex.Unlock()
+			return 0, BufferFlags{}
+		},
+		Checkpoint: &CheckpointConfig[int]{Storage: NewFileCheckpointStorage(dir), Codec: newIntCodec()},
+	})
+	sink = builder.NewStep(StepTerminalConfig[int]{Process: func(int) {}})
+	restarted := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, buffer, sink)
+	if err := restarted.Init(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if restarted.Describe().Steps[0].Buffer.Buffered != 5 {
+		t.Errorf("expected 5 restored tokens, got %d", restarted.Describe().Steps[0].Buffer.Buffered)
+	}
+	if restarted.TokensCount() != 5 {
+		t.Errorf("expected restored tokens to be counted, got %d", restarted.TokensCount())
+	}
+
+	restarted.Run(context.Background())
+	restarted.FeedOne(7)
+	deadline := time.Now().Add(time.Second)
+	for {
+		mutex.Lock()
+		window := captured
+		mutex.Unlock()
+		if reflect.DeepEqual(window, []int{3, 4, 5, 6, 7}) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the restored window to continue, got %v", window)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	restarted.Terminate()
+}
+
+func TestPipeline_Checkpoint_Periodic(t *testing.T) {
+	dir := t.TempDir()
+	builder := &Builder[int]{}
+	buffer := builder.NewStep(StepBufferConfig[int]{
+		Label:                 "window",
+		BufferSize:            5,
+		InputTriggeredProcess: func([]int) (int, BufferFlags) { return 0, BufferFlags{} },
+		Checkpoint: &CheckpointConfig[int]{
+			Storage:  NewFileCheckpointStorage(dir),
+			Codec:    newIntCodec(),
+			Interval: 10 * time.Millisecond,
+		},
+	})
+	sink := builder.NewStep(StepTerminalConfig[int]{Process: func(int) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, buffer, sink)
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+	p.FeedMany([]int{1, 2})
+	waitBuffered(t, p, 2)
+
+	storage := NewFileCheckpointStorage(dir)
+	deadline := time.Now().Add(time.Second)
+	for {
+		data, err := storage.Load("window")
+		if err == nil {
+			tokens, _ := decodeRecords[int](newIntCodec(), data)
+			if reflect.DeepEqual(tokens, []int{1, 2}) {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the checkpoint to be saved periodically")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPipeline_Checkpoint_Errors(t *testing.T) {
+	dir := t.TempDir()
+	builder := &Builder[int]{}
+	buffer := builder.NewStep(StepBufferConfig[int]{
+		Label:                 "window",
+		BufferSize:            5,
+		InputTriggeredProcess: func([]int) (int, BufferFlags) { return 0, BufferFlags{} },
+		Checkpoint:            &CheckpointConfig[int]{Storage: NewFileCheckpointStorage(dir), Codec: newIntCodec()},
+	})
+	sink := builder.NewStep(StepTerminalConfig[int]{Process: func(int) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, buffer, sink)
+	if err := p.Checkpoint(); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState checkpointing before init, got %v", err)
+	}
+
+	NewFileCheckpointStorage(dir).Save("window", []byte{0xff})
+	if err := p.Init(); err == nil {
+		t.Error("expected error restoring a corrupted checkpoint")
+	}
+}
+
+func TestCheckpointConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		label      string
+		checkpoint *CheckpointConfig[int]
+	}{
+		{"MissingStorage", "window", &CheckpointConfig[int]{Codec: newIntCodec()}},
+		{"MissingCodec", "window", &CheckpointConfig[int]{Storage: NewFileCheckpointStorage("dir")}},
+		{"MissingKey", "", &CheckpointConfig[int]{Storage: NewFileCheckpointStorage("dir"), Codec: newIntCodec()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			builder := &Builder[int]{}
+			builder.NewStep(StepBufferConfig[int]{
+				Label:                 tt.label,
+				BufferSize:            5,
+				InputTriggeredProcess: func([]int) (int, BufferFlags) { return 0, BufferFlags{} },
+				Checkpoint:            tt.checkpoint,
+			})
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package pipelines
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrSkipRecord is returned by a decoder of a source to skip the record without stopping the source.
+var ErrSkipRecord = errors.New("skip record")
+
+// Encoder encodes a token into a record.
+type Encoder[I any] interface {
+	Encode(I) ([]byte, error)
+}
+
+// Decoder decodes a record into a token.
+type Decoder[I any] interface {
+	Decode([]byte) (I, error)
+}
+
+// Codec encodes tokens into records and decodes them back.
+type Codec[I any] interface {
+	Encoder[I]
+	Decoder[I]
+}
+
+// HeaderEncoder is implemented by the encoders which write a header before the first record, like the CSV encoder.
+// The writer step writes the header once before its first record, unless the header is empty.
+type HeaderEncoder interface {
+	EncodeHeader() ([]byte, error)
+}
+
+// RecordSplitter is implemented by the decoders of records which can contain new lines, like the CSV decoder.
+// The reader and directory sources split the records using its split function unless a delimiter is configured.
+type RecordSplitter interface {
+	SplitRecords() bufio.SplitFunc
+}
+
+// EncoderFunc is a function used as an Encoder.
+type EncoderFunc[I any] func(I) ([]byte, error)
+
+func (f EncoderFunc[I]) Encode(token I) ([]byte, error) {
+	return f(token)
+}
+
+// DecoderFunc is a function used as a Decoder.
+type DecoderFunc[I any] func([]byte) (I, error)
+
+func (f DecoderFunc[I]) Decode(record []byte) (I, error) {
+	return f(record)
+}
+
+// codec combines an encoder and a decoder.
+type codec[I any] struct {
+	Encoder[I]
+	Decoder[I]
+}
+
+// NewCodec creates a codec from the given encoder and decoder.
+func NewCodec[I any](encoder Encoder[I], decoder Decoder[I]) Codec[I] {
+	if encoder == nil || decoder == nil {
+		panic("encoder and decoder are required")
+	}
+	return codec[I]{Encoder: encoder, Decoder: decoder}
+}
+
+// NewGobCodec creates a codec encoding every token as a standalone gob record.
+// Only the exported fields of the tokens are encoded.
+func NewGobCodec[I any]() Codec[I] {
+	return NewCodec[I](
+		EncoderFunc[I](func(token I) ([]byte, error) {
+			var buf bytes.Buffer
+			err := gob.NewEncoder(&buf).Encode(&token)
+			return buf.Bytes(), err
+		}),
+		DecoderFunc[I](func(record []byte) (I, error) {
+			var token I
+			err := gob.NewDecoder(bytes.NewReader(record)).Decode(&token)
+			return token, err
+		}),
+	)
+}
+
+// envelopeRecord is the encoded form of an envelope.
+type envelopeRecord struct {
+	ID            uint64
+	ParentID      uint64
+	CorrelationID uint64
+	IngestedAt    time.Time
+	Priority      Priority
+	Timings       []StepTiming
+	Attributes    map[string]string
+	Roots         []uint64
+	Value         []byte
+}
+
+// NewEnvelopeCodec creates a codec for envelopes which encodes their metadata along with the value encoded by the given codec.
+func NewEnvelopeCodec[T any](valueCodec Codec[T]) Codec[*Envelope[T]] {
+	if valueCodec == nil {
+		panic("value codec is required")
+	}
+	return NewCodec[*Envelope[T]](
+		EncoderFunc[*Envelope[T]](func(e *Envelope[T]) ([]byte, error) {
+			value, err := valueCodec.Encode(e.Value)
+			if err != nil {
+				return nil, err
+			}
+			record := envelopeRecord{
+				ID:            e.ID(),
+				ParentID:      e.ParentID(),
+				CorrelationID: e.CorrelationID(),
+				IngestedAt:    e.IngestedAt(),
+				Priority:      e.Priority(),
+				Timings:       e.Timings(),
+				Attributes:    e.Attributes(),
+				Roots:         e.getRoots(),
+				Value:         value,
+			}
+			var buf bytes.Buffer
+			err = gob.NewEncoder(&buf).Encode(&record)
+			return buf.Bytes(), err
+		}),
+		DecoderFunc[*Envelope[T]](func(data []byte) (*Envelope[T], error) {
+			var record envelopeRecord
+			if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&record); err != nil {
+				return nil, err
+			}
+			value, err := valueCodec.Decode(record.Value)
+			if err != nil {
+				return nil, err
+			}
+			e := NewEnvelope(value)
+			e.id = record.ID
+			e.parentID = record.ParentID
+			e.correlationID = record.CorrelationID
+			e.ingestedAt = record.IngestedAt
+			e.priority = record.Priority
+			e.timings = record.Timings
+			e.roots = record.Roots
+			if len(record.Attributes) > 0 {
+				e.attributes = record.Attributes
+			}
+			return e, nil
+		}),
+	)
+}
+
+// encodeRecords encodes the tokens into a sequence of records each prefixed by its length.
+func encodeRecords[I any](encoder Encoder[I], tokens []I) ([]byte, error) {
+	var data []byte
+	for _, token := range tokens {
+		record, err := encoder.Encode(token)
+		if err != nil {
+			return nil, err
+		}
+		data = binary.AppendUvarint(data, uint64(len(record)))
+		data = append(data, record...)
+	}
+	return data, nil
+}
+
+// decodeRecords decodes the sequence of records encoded by encodeRecords.
+func decodeRecords[I any](decoder Decoder[I], data []byte) ([]I, error) {
+	var tokens []I
+	for offset := 0; len(data) > 0; {
+		size, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < size {
+			return nil, fmt.Errorf("corrupted record at offset %d", offset)
+		}
+		token, err := decoder.Decode(data[n : n+int(size)])
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+		data = data[n+int(size):]
+		offset += n + int(size)
+	}
+	return tokens, nil
+}
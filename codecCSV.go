@@ -0,0 +1,385 @@
+package pipelines
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CSVConfig is a struct that defines the configuration for the CSV encoder and decoder.
+// The columns are mapped to the exported fields of the token, which has to be a struct or a pointer to a struct.
+// In envelope mode, the columns are mapped to the fields of the value carried by the envelope, so the codecs can be
+// created over *Envelope[T] directly, while the metadata of the envelope is not encoded.
+// The column of a field is named by its `csv` tag, or by the name of the field if it has no tag, and the fields tagged
+// with `csv:"-"` are ignored. The fields can be strings, booleans, numbers, durations, or implement the
+// encoding.TextMarshaler and encoding.TextUnmarshaler interfaces, like time.Time.
+type CSVConfig struct {
+
+	// Comma is the field delimiter. It is ',' if it is not set.
+	Comma rune
+
+	// Header is the names of the columns in order. If it is not set, the decoder reads the names from the first record,
+	// and the encoder writes all the fields of the token in order.
+	Header []string
+
+	// OmitHeader stops the encoder from writing the header before the first record.
+	OmitHeader bool
+}
+
+// csvField is a field of the token mapped to a column.
+type csvField struct {
+	name   string
+	index  []int
+	parse  func(string, reflect.Value) error
+	format func(reflect.Value) (string, error)
+}
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()
+	textMarshalerType   = reflect.TypeFor[encoding.TextMarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	envelopeType        = reflect.TypeFor[envelope]()
+)
+
+// csvStructType returns the struct type of the token, which is either a struct or a pointer to a struct,
+// or an envelope carrying either of them.
+func csvStructType[I any]() reflect.Type {
+	t := reflect.TypeFor[I]()
+	if t.Implements(envelopeType) {
+		value, _ := t.Elem().FieldByName("Value")
+		t = value.Type
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("csv token has to be a struct or a pointer to a struct, got %s", reflect.TypeFor[I]()))
+	}
+	return t
+}
+
+// csvStruct returns the struct of the token mapped to the columns, unwrapping the envelope and the pointers.
+// The nil pointers are allocated if allocate is set, otherwise it returns false once it finds a nil pointer.
+func csvStruct(token reflect.Value, allocate bool) (reflect.Value, bool) {
+	elem := func(v reflect.Value) (reflect.Value, bool) {
+		if v.Kind() != reflect.Pointer {
+			return v, true
+		}
+		if v.IsNil() {
+			if !allocate {
+				return v, false
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return v.Elem(), true
+	}
+	if token.Type().Implements(envelopeType) {
+		e, ok := elem(token)
+		if !ok {
+			return e, false
+		}
+		token = e.FieldByName("Value")
+	}
+	return elem(token)
+}
+
+// csvFields returns the fields of the struct mapped to columns in order.
+// It panics if two fields are mapped to the same column, like the fields promoted from different embedded structs.
+func csvFields(t reflect.Type) []*csvField {
+	var fields []*csvField
+	names := make(map[string]string)
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous || promotedThroughPointer(t, f.Index) {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("csv"); ok {
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		if other, ok := names[name]; ok {
+			panic(fmt.Sprintf("csv fields %s and %s are mapped to the same column %q", other, f.Name, name))
+		}
+		names[name] = f.Name
+		field := &csvField{name: name, index: f.Index}
+		if !setCSVConverters(field, f.Type) {
+			panic(fmt.Sprintf("unsupported type %s of csv field %s", f.Type, f.Name))
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// promotedThroughPointer checks if the field is promoted from a struct embedded by a pointer, which can be nil.
+func promotedThroughPointer(t reflect.Type, index []int) bool {
+	for _, i := range index[:len(index)-1] {
+		f := t.Field(i)
+		if f.Type.Kind() == reflect.Pointer {
+			return true
+		}
+		t = f.Type
+	}
+	return false
+}
+
+// setCSVConverters sets the functions parsing and formatting the values of the field based on its type.
+func setCSVConverters(field *csvField, t reflect.Type) bool {
+	if t == durationType {
+		field.parse = func(s string, v reflect.Value) error {
+			d, err := time.ParseDuration(s)
+			v.SetInt(int64(d))
+			return err
+		}
+		field.format = func(v reflect.Value) (string, error) {
+			return time.Duration(v.Int()).String(), nil
+		}
+		return true
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) && t.Implements(textMarshalerType) {
+		field.parse = func(s string, v reflect.Value) error {
+			return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+		}
+		field.format = func(v reflect.Value) (string, error) {
+			text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+			return string(text), err
+		}
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		field.parse = func(s string, v reflect.Value) error {
+			v.SetString(s)
+			return nil
+		}
+		field.format = func(v reflect.Value) (string, error) {
+			return v.String(), nil
+		}
+	case reflect.Bool:
+		field.parse = func(s string, v reflect.Value) error {
+			b, err := strconv.ParseBool(s)
+			v.SetBool(b)
+			return err
+		}
+		field.format = func(v reflect.Value) (string, error) {
+			return strconv.FormatBool(v.Bool()), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		field.parse = func(s string, v reflect.Value) error {
+			i, err := strconv.ParseInt(s, 10, t.Bits())
+			v.SetInt(i)
+			return err
+		}
+		field.format = func(v reflect.Value) (string, error) {
+			return strconv.FormatInt(v.Int(), 10), nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		field.parse = func(s string, v reflect.Value) error {
+			u, err := strconv.ParseUint(s, 10, t.Bits())
+			v.SetUint(u)
+			return err
+		}
+		field.format = func(v reflect.Value) (string, error) {
+			return strconv.FormatUint(v.Uint(), 10), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		field.parse = func(s string, v reflect.Value) error {
+			f, err := strconv.ParseFloat(s, t.Bits())
+			v.SetFloat(f)
+			return err
+		}
+		field.format = func(v reflect.Value) (string, error) {
+			return strconv.FormatFloat(v.Float(), 'g', -1, t.Bits()), nil
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// csvDecoder decodes CSV records into tokens. It reads the header from the first record if it is not configured.
+type csvDecoder[I any] struct {
+	comma  rune
+	fields map[string]*csvField
+
+	// mutex guards the columns read from the header.
+	mutex sync.Mutex
+
+	// columns are the fields of the columns in order, where the columns not mapped to fields are nil.
+	// It is nil till the header is read.
+	columns []*csvField
+}
+
+// NewCSVDecoder creates a decoder decoding every CSV record into a token. Unless the header is configured, the first
+// record is read as the header and skipped, so every decoder has to be used for the records of a single input.
+// The empty values are decoded as the zero values of the fields, and the columns not mapped to fields are ignored.
+// It implements RecordSplitter, so that the reader and directory sources don't split the quoted values containing new lines.
+func NewCSVDecoder[I any](config CSVConfig) Decoder[I] {
+	fields := make(map[string]*csvField)
+	for _, field := range csvFields(csvStructType[I]()) {
+		fields[field.name] = field
+	}
+	decoder := &csvDecoder[I]{comma: config.Comma, fields: fields}
+	if config.Header != nil {
+		decoder.columns = decoder.mapColumns(config.Header)
+	}
+	return decoder
+}
+
+// mapColumns returns the fields of the columns in order.
+func (d *csvDecoder[I]) mapColumns(header []string) []*csvField {
+	columns := make([]*csvField, len(header))
+	for i, name := range header {
+		columns[i] = d.fields[name]
+	}
+	return columns
+}
+
+func (d *csvDecoder[I]) Decode(record []byte) (I, error) {
+	var token I
+	values, err := readCSVRecord(record, d.comma)
+	if errors.Is(err, io.EOF) {
+		return token, ErrSkipRecord
+	}
+	if err != nil {
+		return token, err
+	}
+
+	d.mutex.Lock()
+	columns := d.columns
+	if columns == nil {
+		// the byte order mark written by some spreadsheet exports is not a part of the first column name.
+		values[0] = strings.TrimPrefix(values[0], "\ufeff")
+		d.columns = d.mapColumns(values)
+	}
+	d.mutex.Unlock()
+	if columns == nil {
+		return token, ErrSkipRecord
+	}
+
+	target, _ := csvStruct(reflect.ValueOf(&token).Elem(), true)
+	for i, value := range values {
+		if i >= len(columns) || columns[i] == nil || value == "" {
+			continue
+		}
+		if err := columns[i].parse(value, target.FieldByIndex(columns[i].index)); err != nil {
+			return token, fmt.Errorf("column %q: %w", columns[i].name, err)
+		}
+	}
+	return token, nil
+}
+
+func (d *csvDecoder[I]) SplitRecords() bufio.SplitFunc {
+	return splitCSVRecords
+}
+
+// splitCSVRecords splits the CSV records at the line ends which are not in quoted values.
+// The escaped quotes in quoted values are two quotes, so they don't change whether the value is quoted.
+func splitCSVRecords(data []byte, atEOF bool) (int, []byte, error) {
+	quoted := false
+	for i, b := range data {
+		switch {
+		case b == '"':
+			quoted = !quoted
+		case b == '\n' && !quoted:
+			return i + 1, bytes.TrimSuffix(data[:i], []byte("\r")), nil
+		}
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), bytes.TrimSuffix(data, []byte("\r")), nil
+	}
+	return 0, nil, nil
+}
+
+// readCSVRecord reads the values of a single CSV record.
+func readCSVRecord(record []byte, comma rune) ([]string, error) {
+	reader := csv.NewReader(bytes.NewReader(record))
+	if comma != 0 {
+		reader.Comma = comma
+	}
+	reader.FieldsPerRecord = -1
+	return reader.Read()
+}
+
+// csvEncoder encodes tokens into CSV records.
+type csvEncoder[I any] struct {
+	comma      rune
+	columns    []*csvField
+	omitHeader bool
+}
+
+// NewCSVEncoder creates an encoder encoding every token into a CSV record without the trailing new line.
+// Unless the header is omitted, it implements HeaderEncoder so that the writer step writes the header first.
+func NewCSVEncoder[I any](config CSVConfig) Encoder[I] {
+	fields := csvFields(csvStructType[I]())
+	columns := fields
+	if config.Header != nil {
+		columns = make([]*csvField, len(config.Header))
+		for i, name := range config.Header {
+			for _, field := range fields {
+				if field.name == name {
+					columns[i] = field
+				}
+			}
+			if columns[i] == nil {
+				panic(fmt.Sprintf("csv column %q is not mapped to a field", name))
+			}
+		}
+	}
+	return &csvEncoder[I]{comma: config.Comma, columns: columns, omitHeader: config.OmitHeader}
+}
+
+func (e *csvEncoder[I]) EncodeHeader() ([]byte, error) {
+	if e.omitHeader {
+		return nil, nil
+	}
+	names := make([]string, len(e.columns))
+	for i, column := range e.columns {
+		names[i] = column.name
+	}
+	return writeCSVRecord(names, e.comma)
+}
+
+func (e *csvEncoder[I]) Encode(token I) ([]byte, error) {
+	source, ok := csvStruct(reflect.ValueOf(&token).Elem(), false)
+	if !ok {
+		return nil, errors.New("nil token")
+	}
+	values := make([]string, len(e.columns))
+	for i, column := range e.columns {
+		value, err := column.format(source.FieldByIndex(column.index))
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %w", column.name, err)
+		}
+		values[i] = value
+	}
+	return writeCSVRecord(values, e.comma)
+}
+
+// writeCSVRecord writes the values as a single CSV record without the trailing new line.
+func writeCSVRecord(values []string, comma rune) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if comma != 0 {
+		writer.Comma = comma
+	}
+	writer.Write(values)
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,203 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type csvRecord struct {
+	Name     string        `csv:"name"`
+	Count    int           `csv:"count"`
+	Price    float64       `csv:"price"`
+	Active   bool          `csv:"active"`
+	Timeout  time.Duration `csv:"timeout"`
+	Created  time.Time     `csv:"created"`
+	Internal string        `csv:"-"`
+	Note     string
+}
+
+func TestCSVDecoder(t *testing.T) {
+	decoder := NewCSVDecoder[*csvRecord](CSVConfig{})
+	records := []string{
+		"\ufeffname,unknown,count,price,active,timeout,created,Note",
+		`"a, b",x,3,1.5,true,2s,2024-01-02T03:04:05Z,`,
+		"",
+		"c,x,,,,,,note",
+	}
+
+	var tokens []*csvRecord
+	for _, record := range records {
+		token, err := decoder.Decode([]byte(record))
+		if errors.Is(err, ErrSkipRecord) {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	if len(tokens) != 2 {
+		t.Fatalf("expected 2 tokens, got %d", len(tokens))
+	}
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	first := *tokens[0]
+	if first.Name != "a, b" || first.Count != 3 || first.Price != 1.5 || !first.Active || first.Timeout != 2*time.Second || !first.Created.Equal(created) {
+		t.Errorf("unexpected first token: %+v", first)
+	}
+	if *tokens[1] != (csvRecord{Name: "c", Note: "note"}) {
+		t.Errorf("expected the empty values to be zero, got %+v", *tokens[1])
+	}
+
+	if _, err := decoder.Decode([]byte("d,x,many")); err == nil || !strings.Contains(err.Error(), `"count"`) {
+		t.Errorf("expected the parsing error of the count column, got %v", err)
+	}
+}
+
+func TestCSVDecoder_Header(t *testing.T) {
+	decoder := NewCSVDecoder[csvRecord](CSVConfig{Comma: ';', Header: []string{"count", "name"}})
+	token, err := decoder.Decode([]byte("7;a"))
+	if err != nil || token.Count != 7 || token.Name != "a" {
+		t.Errorf("expected the first record to be decoded using the configured header, got %+v, %v", token, err)
+	}
+}
+
+func TestCSVEncoder(t *testing.T) {
+	encoder := NewCSVEncoder[csvRecord](CSVConfig{}).(HeaderEncoder)
+	header, err := encoder.EncodeHeader()
+	if err != nil || string(header) != "name,count,price,active,timeout,created,Note" {
+		t.Errorf("unexpected header: %s, %v", header, err)
+	}
+
+	record, err := encoder.(Encoder[csvRecord]).Encode(csvRecord{Name: "a, b", Count: 3, Price: 1.5, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(record) != `"a, b",3,1.5,false,1s,0001-01-01T00:00:00Z,` {
+		t.Errorf("unexpected record: %s", record)
+	}
+
+	selected := NewCSVEncoder[*csvRecord](CSVConfig{Comma: '\t', Header: []string{"count", "name"}, OmitHeader: true})
+	if header, _ := selected.(HeaderEncoder).EncodeHeader(); header != nil {
+		t.Errorf("expected no header, got %s", header)
+	}
+	if record, _ := selected.Encode(&csvRecord{Name: "a", Count: 3}); string(record) != "3\ta" {
+		t.Errorf("unexpected record: %q", record)
+	}
+	if _, err := selected.Encode(nil); err == nil {
+		t.Errorf("expected an error for a nil token")
+	}
+}
+
+func TestCSVCodec_Envelope(t *testing.T) {
+	decoder := NewCSVDecoder[*Envelope[csvRecord]](CSVConfig{Header: []string{"name", "count"}})
+	token, err := decoder.Decode([]byte("a,3"))
+	if err != nil || token.Value.Name != "a" || token.Value.Count != 3 {
+		t.Errorf("expected the value of the envelope to be decoded, got %+v, %v", token, err)
+	}
+
+	encoder := NewCSVEncoder[*Envelope[*csvRecord]](CSVConfig{Header: []string{"count", "name"}})
+	if record, err := encoder.Encode(NewEnvelope(&csvRecord{Name: "b", Count: 4})); err != nil || string(record) != "4,b" {
+		t.Errorf("expected the value of the envelope to be encoded, got %q, %v", record, err)
+	}
+	if _, err := encoder.Encode(nil); err == nil {
+		t.Errorf("expected an error for a nil envelope")
+	}
+	if _, err := encoder.Encode(NewEnvelope[*csvRecord](nil)); err == nil {
+		t.Errorf("expected an error for a nil value")
+	}
+}
+
+func TestCSVDecoder_MultilineValues(t *testing.T) {
+	source := NewReaderSource(ReaderSourceConfig[csvRecord]{
+		Reader:  strings.NewReader("name,Note\r\n\"a\nb\",\"say \"\"hi\"\"\r\nthere\"\r\nc,d"),
+		Decoder: NewCSVDecoder[csvRecord](CSVConfig{}),
+	})
+	tokens, err := collectSource(source)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tokens) != 2 {
+		t.Fatalf("expected 2 tokens, got %+v", tokens)
+	}
+	// the csv reader normalizes the quoted line ends to new lines.
+	if tokens[0].Name != "a\nb" || tokens[0].Note != "say \"hi\"\nthere" {
+		t.Errorf("unexpected first token: %+v", tokens[0])
+	}
+	if tokens[1].Name != "c" || tokens[1].Note != "d" {
+		t.Errorf("unexpected second token: %+v", tokens[1])
+	}
+}
+
+func TestCSVCodec_Invalid(t *testing.T) {
+	tests := map[string]func(){
+		"not a struct":   func() { NewCSVDecoder[int](CSVConfig{}) },
+		"unsupported":    func() { NewCSVEncoder[struct{ Values []int }](CSVConfig{}) },
+		"unknown column": func() { NewCSVEncoder[csvRecord](CSVConfig{Header: []string{"missing"}}) },
+		"duplicate column": func() {
+			NewCSVDecoder[struct {
+				csvRecord
+				Label string `csv:"name"`
+			}](CSVConfig{})
+		},
+	}
+	for name, create := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected a panic")
+				}
+			}()
+			create()
+		})
+	}
+}
+
+func TestPipeline_CSVToJSONLines(t *testing.T) {
+	out := &lockedBuffer{}
+	builder := &Builder[csvRecord]{}
+	writer := builder.NewStep(StepWriterConfig[csvRecord]{Writer: out, Encoder: NewJSONCodec[csvRecord](), FlushCount: 1})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, writer)
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+
+	source := NewReaderSource(ReaderSourceConfig[csvRecord]{
+		Reader:  strings.NewReader("name,count\na,1\nb,2\n"),
+		Decoder: NewCSVDecoder[csvRecord](CSVConfig{}),
+	})
+	if err := p.FeedFrom(context.Background(), source); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p.WaitTillDone()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"Name":"a"`) || !strings.Contains(lines[1], `"Count":2`) {
+		t.Errorf("unexpected output: %s", out.String())
+	}
+}
+
+func TestPipeline_CSVWriter(t *testing.T) {
+	out := &lockedBuffer{}
+	builder := &Builder[csvRecord]{}
+	writer := builder.NewStep(StepWriterConfig[csvRecord]{
+		Writer:     out,
+		Encoder:    NewCSVEncoder[csvRecord](CSVConfig{Header: []string{"name", "count"}}),
+		FlushCount: 1,
+	})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, writer)
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+
+	p.FeedMany([]csvRecord{{Name: "a", Count: 1}})
+	p.WaitTillDone()
+	p.FeedMany([]csvRecord{{Name: "b", Count: 2}})
+	p.WaitTillDone()
+	if out.String() != "name,count\na,1\nb,2\n" {
+		t.Errorf("expected the header to be written once, got %q", out.String())
+	}
+}
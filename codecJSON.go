@@ -0,0 +1,24 @@
+package pipelines
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// NewJSONCodec creates a codec encoding every token as a single line JSON record, which is the format of JSON Lines
+// when the records are separated by new lines. Blank records are skipped by the decoder.
+func NewJSONCodec[I any]() Codec[I] {
+	return NewCodec[I](
+		EncoderFunc[I](func(token I) ([]byte, error) {
+			return json.Marshal(token)
+		}),
+		DecoderFunc[I](func(record []byte) (I, error) {
+			var token I
+			if len(bytes.TrimSpace(record)) == 0 {
+				return token, ErrSkipRecord
+			}
+			err := json.Unmarshal(record, &token)
+			return token, err
+		}),
+	)
+}
@@ -0,0 +1,33 @@
+package pipelines
+
+import (
+	"errors"
+	"testing"
+)
+
+type jsonRecord struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestJSONCodec(t *testing.T) {
+	codec := NewJSONCodec[jsonRecord]()
+	record, err := codec.Encode(jsonRecord{Name: "a", Count: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(record) != `{"name":"a","count":2}` {
+		t.Errorf("unexpected record: %s", record)
+	}
+
+	token, err := codec.Decode(record)
+	if err != nil || token != (jsonRecord{Name: "a", Count: 2}) {
+		t.Errorf("unexpected token: %+v, %v", token, err)
+	}
+	if _, err := codec.Decode([]byte("  ")); !errors.Is(err, ErrSkipRecord) {
+		t.Errorf("expected ErrSkipRecord for a blank record, got %v", err)
+	}
+	if _, err := codec.Decode([]byte("{")); err == nil || errors.Is(err, ErrSkipRecord) {
+		t.Errorf("expected a syntax error, got %v", err)
+	}
+}
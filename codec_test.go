@@ -0,0 +1,85 @@
+package pipelines
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type codecTestRecord struct {
+	Name  string
+	Value int
+}
+
+func TestGobCodec(t *testing.T) {
+	codec := NewGobCodec[codecTestRecord]()
+	data, err := codec.Encode(codecTestRecord{Name: "a", Value: 5})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decoded, err := codec.Decode(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decoded != (codecTestRecord{Name: "a", Value: 5}) {
+		t.Errorf("unexpected decoded record: %+v", decoded)
+	}
+
+	if _, err := codec.Decode([]byte("invalid")); err == nil {
+		t.Error("expected error decoding invalid record")
+	}
+}
+
+func TestFuncCodec(t *testing.T) {
+	codec := NewCodec[int](
+		EncoderFunc[int](func(i int) ([]byte, error) { return []byte(strconv.Itoa(i)), nil }),
+		DecoderFunc[int](func(b []byte) (int, error) { return strconv.Atoi(string(b)) }),
+	)
+	data, _ := codec.Encode(42)
+	if string(data) != "42" {
+		t.Errorf("expected 42, got %s", data)
+	}
+	if i, err := codec.Decode([]byte("7")); err != nil || i != 7 {
+		t.Errorf("expected 7, got %d %v", i, err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for missing decoder")
+		}
+	}()
+	NewCodec[int](codec, nil)
+}
+
+func TestEnvelopeCodec(t *testing.T) {
+	codec := NewEnvelopeCodec(NewGobCodec[codecTestRecord]())
+
+	e := NewEnvelope(codecTestRecord{Name: "b", Value: 3})
+	stampEnvelope(e)
+	e.SetPriority(PriorityHigh)
+	e.SetAttribute("source", "sensor")
+	e.addTiming("step", time.Now())
+
+	data, err := codec.Encode(e)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decoded, err := codec.Decode(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if decoded.Value != e.Value || decoded.ID() != e.ID() || decoded.Priority() != PriorityHigh {
+		t.Errorf("unexpected decoded envelope: %+v", decoded)
+	}
+	if !decoded.IngestedAt().Equal(e.IngestedAt()) {
+		t.Errorf("expected ingestion time %s, got %s", e.IngestedAt(), decoded.IngestedAt())
+	}
+	if !reflect.DeepEqual(decoded.Attributes(), e.Attributes()) {
+		t.Errorf("expected attributes %v, got %v", e.Attributes(), decoded.Attributes())
+	}
+	if len(decoded.Timings()) != 1 || decoded.Timings()[0].Label != "step" {
+		t.Errorf("unexpected decoded timings: %v", decoded.Timings())
+	}
+}
@@ -0,0 +1,223 @@
+package pipelines
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PipelineDescription is a snapshot of the topology of the pipeline.
+type PipelineDescription struct {
+
+	// Steps is the list of steps in the order of execution.
+	Steps []StepDescription
+
+	// Edges is the list of channels connecting the pipeline input, the steps, and the pipeline output.
+	Edges []EdgeDescription
+}
+
+// StepDescription describes a single step of the pipeline.
+type StepDescription struct {
+
+	// Label is the label of the step set by the user.
+	Label string
+
+	// Type is the type of the step (basic, filter, fragmenter, terminal, buffer, rate limiter, writer or custom).
+	Type string
+
+	// Replicas is the number of replicas running the step.
+	Replicas uint16
+
+	// InputChannelSize is the buffer size of the input channel to the step.
+	InputChannelSize uint16
+
+	// QueueLength is the number of tokens waiting in the input channel or the input queue of the step.
+	QueueLength int
+
+	// Spilled is the number of tokens spilled to disk by the input queue of the step.
+	Spilled int
+
+	// Dropped is the number of tokens dropped by the overflow policy of the input queue of the step.
+	Dropped uint64
+
+	// Diverted is the number of tokens diverted by the overflow policy of the input queue of the step.
+	Diverted uint64
+
+	// Buffer describes the buffer settings and is set for buffer steps only.
+	Buffer *BufferDescription
+}
+
+// BufferDescription describes the settings and the state of a buffer step.
+type BufferDescription struct {
+
+	// BufferSize is the max size of the buffer.
+	BufferSize int
+
+	// Buffered is the number of tokens currently retained in the buffer.
+	Buffered int
+
+	// PassThrough indicates whether the input is passed to the following step or not.
+	PassThrough bool
+
+	// InputTriggered indicates whether the step has an input triggered process.
+	InputTriggered bool
+
+	// TimeTriggered indicates whether the step has a time triggered process.
+	TimeTriggered bool
+
+	// TimeTriggeredProcessInterval is the interval at which the time triggered process is called.
+	TimeTriggeredProcessInterval time.Duration
+}
+
+// EdgeDescription describes a channel feeding a step or the output of the pipeline.
+type EdgeDescription struct {
+
+	// From is the index of the step sending to the channel. It is -1 for the pipeline input.
+	From int
+
+	// To is the index of the step receiving from the channel. It is -1 for the pipeline output.
+	To int
+
+	// Capacity is the buffer size of the channel.
+	Capacity int
+
+	// Length is the number of tokens currently waiting in the channel.
+	Length int
+}
+
+// describeStepType returns the type name of the step.
+func describeStepType[I any](step IStep[I]) string {
+	switch step.(type) {
+	case *stepBasic[I]:
+		return "basic"
+	case *stepFilter[I]:
+		return "filter"
+	case *stepFragmenter[I]:
+		return "fragmenter"
+	case *stepTerminal[I]:
+		return "terminal"
+	case *stepBuffer[I]:
+		return "buffer"
+	case *stepRateLimiter[I]:
+		return "rate limiter"
+	case *stepWriter[I]:
+		return "writer"
+	default:
+		return "custom"
+	}
+}
+
+func (p *pipeline[I]) Describe() PipelineDescription {
+	description := PipelineDescription{
+		Steps: make([]StepDescription, len(p.steps)),
+		Edges: make([]EdgeDescription, len(p.steps)),
+	}
+	for i, step := range p.steps {
+		description.Edges[i] = EdgeDescription{From: i - 1, To: i}
+		if step == nil {
+			continue
+		}
+		length, capacity := p.queueDepth(i)
+		description.Steps[i] = StepDescription{
+			Label:            step.GetLabel(),
+			Type:             describeStepType(step),
+			Replicas:         p.runningReplicas(i),
+			InputChannelSize: step.GetInputChannelSize(),
+			QueueLength:      length,
+		}
+		if p.queues != nil && p.queues[i] != nil {
+			description.Steps[i].Spilled = p.queues[i].spilled()
+			description.Steps[i].Dropped = p.queues[i].dropped.Load()
+			description.Steps[i].Diverted = p.queues[i].diverted.Load()
+		}
+		if buffer, ok := step.(*stepBuffer[I]); ok {
+			description.Steps[i].Buffer = buffer.describe()
+		}
+		description.Edges[i].Capacity = capacity
+		description.Edges[i].Length = length
+	}
+	// the last step passing its tokens on sends them to the pipeline output.
+	last := len(p.steps) - 1
+	if last >= 0 {
+		if _, ok := p.steps[last].(outputStep); ok {
+			description.Edges = append(description.Edges, EdgeDescription{From: last, To: -1, Capacity: cap(p.exit), Length: len(p.exit)})
+		}
+	}
+	return description
+}
+
+// nodeName returns a readable name for the step at the given index.
+func (d PipelineDescription) nodeName(index int) string {
+	if index < 0 {
+		return "input"
+	}
+	step := d.Steps[index]
+	if step.Label != "" {
+		return step.Label
+	}
+	return fmt.Sprintf("%s %d", step.Type, index)
+}
+
+// nodeID returns the identifier of the node used by the renderers.
+func nodeID(index int) string {
+	if index < 0 {
+		return "input"
+	}
+	return fmt.Sprintf("step%d", index)
+}
+
+// targetID returns the identifier of the node receiving from an edge.
+func targetID(index int) string {
+	if index < 0 {
+		return "output"
+	}
+	return nodeID(index)
+}
+
+// hasOutput reports whether the description has an edge to the pipeline output.
+func (d PipelineDescription) hasOutput() bool {
+	for _, edge := range d.Edges {
+		if edge.To < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// DOT renders the description as a Graphviz DOT graph with the queue depths annotated on the edges.
+func (d PipelineDescription) DOT() string {
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+	var sb strings.Builder
+	sb.WriteString("digraph pipeline {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	sb.WriteString("  input [shape=circle, label=\"input\"];\n")
+	if d.hasOutput() {
+		sb.WriteString("  output [shape=circle, label=\"output\"];\n")
+	}
+	for i, step := range d.Steps {
+		fmt.Fprintf(&sb, "  %s [shape=box, label=\"%s\\n%s x%d\"];\n", nodeID(i), escape.Replace(d.nodeName(i)), step.Type, step.Replicas)
+	}
+	for _, edge := range d.Edges {
+		fmt.Fprintf(&sb, "  %s -> %s [label=\"%d/%d\"];\n", nodeID(edge.From), targetID(edge.To), edge.Length, edge.Capacity)
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// Mermaid renders the description as a Mermaid flowchart with the queue depths annotated on the edges.
+func (d PipelineDescription) Mermaid() string {
+	escape := strings.NewReplacer(`"`, "#quot;", "|", "#124;", "\r\n", "<br/>", "\n", "<br/>")
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+	sb.WriteString("  input((input))\n")
+	if d.hasOutput() {
+		sb.WriteString("  output((output))\n")
+	}
+	for i, step := range d.Steps {
+		fmt.Fprintf(&sb, "  %s[\"%s<br/>%s x%d\"]\n", nodeID(i), escape.Replace(d.nodeName(i)), step.Type, step.Replicas)
+	}
+	for _, edge := range d.Edges {
+		fmt.Fprintf(&sb, "  %s -->|%d/%d| %s\n", nodeID(edge.From), edge.Length, edge.Capacity, targetID(edge.To))
+	}
+	return sb.String()
+}
@@ -0,0 +1,213 @@
+package pipelines
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPipeline_Describe(t *testing.T) {
+	builder := &Builder[int]{}
+	doubleStep := builder.NewStep(StepBasicConfig[int]{
+		Label:    "double",
+		Replicas: 2,
+		Process:  func(i int) int { return i * 2 },
+	})
+	bufferStep := builder.NewStep(StepBufferConfig[int]{
+		Label:                        "buffer",
+		InputChannelSize:             5,
+		BufferSize:                   3,
+		PassThrough:                  true,
+		TimeTriggeredProcess:         func([]int) (int, BufferFlags) { return 0, BufferFlags{} },
+		TimeTriggeredProcessInterval: time.Second,
+	})
+	printStep := builder.NewStep(StepTerminalConfig[int]{
+		Process: func(int) {},
+	})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, doubleStep, bufferStep, printStep)
+	p.Init()
+
+	// feeding without running so that the tokens stay in the input channel.
+	p.FeedMany([]int{1, 2, 3})
+
+	description := p.Describe()
+
+	if len(description.Steps) != 3 {
+		t.Fatalf("expected 3 steps, got %d", len(description.Steps))
+	}
+	if len(description.Edges) != 3 {
+		t.Fatalf("expected 3 edges, got %d", len(description.Edges))
+	}
+
+	double := description.Steps[0]
+	if double.Label != "double" || double.Type != "basic" || double.Replicas != 2 {
+		t.Errorf("unexpected description of the first step: %+v", double)
+	}
+	if double.InputChannelSize != 10 || double.QueueLength != 3 {
+		t.Errorf("expected queue of 3/10, got %d/%d", double.QueueLength, double.InputChannelSize)
+	}
+
+	buffer := description.Steps[1]
+	if buffer.Type != "buffer" || buffer.Buffer == nil {
+		t.Fatalf("expected buffer description, got %+v", buffer)
+	}
+	if buffer.Buffer.BufferSize != 3 || !buffer.Buffer.PassThrough || !buffer.Buffer.TimeTriggered || buffer.Buffer.InputTriggered {
+		t.Errorf("unexpected buffer description: %+v", buffer.Buffer)
+	}
+	if buffer.Buffer.TimeTriggeredProcessInterval != time.Second {
+		t.Errorf("expected interval to be 1s, got %s", buffer.Buffer.TimeTriggeredProcessInterval)
+	}
+
+	if description.Steps[2].Type != "terminal" {
+		t.Errorf("expected terminal step, got %s", description.Steps[2].Type)
+	}
+
+	first := description.Edges[0]
+	if first.From != -1 || first.To != 0 || first.Length != 3 || first.Capacity != 10 {
+		t.Errorf("unexpected input edge: %+v", first)
+	}
+	second := description.Edges[1]
+	if second.From != 0 || second.To != 1 || second.Capacity != 5 {
+		t.Errorf("unexpected second edge: %+v", second)
+	}
+}
+
+func TestPipeline_Describe_CustomStep(t *testing.T) {
+	p := &pipeline[int]{
+		steps:              []IStep[int]{&mockStep[int]{label: "mock", replicas: 1}, &mockStep[int]{replicas: 1, finalStep: true}},
+		defaultChannelSize: 10,
+	}
+	description := p.Describe()
+	if description.Steps[0].Type != "custom" {
+		t.Errorf("expected custom step type, got %s", description.Steps[0].Type)
+	}
+}
+
+func TestPipelineDescription_DOT(t *testing.T) {
+	builder := &Builder[int]{}
+	double := builder.NewStep(StepBasicConfig[int]{
+		Label:    "double",
+		Replicas: 2,
+		Process:  func(i int) int { return i * 2 },
+	})
+	buffer := builder.NewStep(StepBufferConfig[int]{
+		Label:                        "buffer",
+		InputChannelSize:             5,
+		BufferSize:                   3,
+		PassThrough:                  true,
+		TimeTriggeredProcess:         func([]int) (int, BufferFlags) { return 0, BufferFlags{} },
+		TimeTriggeredProcessInterval: time.Second,
+	})
+	printStep := builder.NewStep(StepTerminalConfig[int]{
+		Process: func(int) {},
+	})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, double, buffer, printStep)
+	p.Init()
+	p.FeedOne(1)
+
+	dot := p.Describe().DOT()
+
+	expected := []string{
+		"digraph pipeline {",
+		`step0 [shape=box, label="double\nbasic x2"];`,
+		`step2 [shape=box, label="terminal 2\nterminal x1"];`,
+		`input -> step0 [label="1/10"];`,
+		`step0 -> step1 [label="0/5"];`,
+		`step1 -> step2 [label="0/10"];`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(dot, e) {
+			t.Errorf("expected DOT output to contain %q, got:\n%s", e, dot)
+		}
+	}
+}
+
+func TestPipelineDescription_Mermaid(t *testing.T) {
+	builder := &Builder[int]{}
+	double := builder.NewStep(StepBasicConfig[int]{
+		Label:    "double",
+		Replicas: 2,
+		Process:  func(i int) int { return i * 2 },
+	})
+	buffer := builder.NewStep(StepBufferConfig[int]{
+		Label:                        "buffer",
+		InputChannelSize:             5,
+		BufferSize:                   3,
+		PassThrough:                  true,
+		TimeTriggeredProcess:         func([]int) (int, BufferFlags) { return 0, BufferFlags{} },
+		TimeTriggeredProcessInterval: time.Second,
+	})
+	printStep := builder.NewStep(StepTerminalConfig[int]{
+		Process: func(int) {},
+	})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, double, buffer, printStep)
+	p.Init()
+	p.FeedOne(1)
+
+	mermaid := p.Describe().Mermaid()
+
+	expected := []string{
+		"flowchart LR",
+		`step1["buffer<br/>buffer x1"]`,
+		"input -->|1/10| step0",
+		"step1 -->|0/10| step2",
+	}
+	for _, e := range expected {
+		if !strings.Contains(mermaid, e) {
+			t.Errorf("expected Mermaid output to contain %q, got:\n%s", e, mermaid)
+		}
+	}
+}
+
+func TestPipeline_Describe_Output(t *testing.T) {
+	builder := &Builder[int]{}
+	double := builder.NewStep(StepBasicConfig[int]{
+		Label:   "double",
+		Process: func(i int) int { return i * 2 },
+	})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, double)
+	p.Init()
+
+	description := p.Describe()
+
+	// the last step passing its tokens on is connected to the pipeline output.
+	if len(description.Edges) != 2 {
+		t.Fatalf("expected 2 edges, got %d", len(description.Edges))
+	}
+	output := description.Edges[1]
+	if output.From != 0 || output.To != -1 || output.Capacity != 10 {
+		t.Errorf("unexpected output edge: %+v", output)
+	}
+
+	dot := description.DOT()
+	for _, e := range []string{`output [shape=circle, label="output"];`, `step0 -> output [label="0/10"];`} {
+		if !strings.Contains(dot, e) {
+			t.Errorf("expected DOT output to contain %q, got:\n%s", e, dot)
+		}
+	}
+	mermaid := description.Mermaid()
+	for _, e := range []string{"output((output))", "step0 -->|0/10| output"} {
+		if !strings.Contains(mermaid, e) {
+			t.Errorf("expected Mermaid output to contain %q, got:\n%s", e, mermaid)
+		}
+	}
+}
+
+func TestPipelineDescription_EscapeLabels(t *testing.T) {
+	description := PipelineDescription{
+		Steps: []StepDescription{{Label: "two\nlines \"quoted\"", Type: "terminal", Replicas: 1}},
+		Edges: []EdgeDescription{{From: -1, To: 0, Capacity: 10}},
+	}
+
+	dot := description.DOT()
+	if e := `step0 [shape=box, label="two\nlines \"quoted\"\nterminal x1"];`; !strings.Contains(dot, e) {
+		t.Errorf("expected DOT output to contain %q, got:\n%s", e, dot)
+	}
+	if strings.Contains(dot, "output") {
+		t.Errorf("expected no output node without an output edge, got:\n%s", dot)
+	}
+	mermaid := description.Mermaid()
+	if e := `step0["two<br/>lines #quot;quoted#quot;<br/>terminal x1"]`; !strings.Contains(mermaid, e) {
+		t.Errorf("expected Mermaid output to contain %q, got:\n%s", e, mermaid)
+	}
+}
@@ -0,0 +1,259 @@
+package pipelines
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// envelopeIDs generates the unique ids assigned to the envelopes fed to any pipeline.
+var envelopeIDs atomic.Uint64
+
+// StepTiming is the time a token spent in the process of a single step.
+type StepTiming struct {
+
+	// Label is the label of the step which processed the token.
+	Label string
+
+	// Start is the time the step started processing the token.
+	Start time.Time
+
+	// Duration is the time taken by the step process.
+	Duration time.Duration
+}
+
+// Envelope wraps a token with metadata that travels with it through the pipeline.
+// To run the pipeline in envelope mode, build it over *Envelope[T] instead of T.
+type Envelope[T any] struct {
+	envelopeHeader
+
+	// Value is the token carried by the envelope.
+	Value T
+}
+
+// NewEnvelope creates a new envelope for the given value. The id and the ingestion time are set once it is fed to the pipeline.
+func NewEnvelope[T any](value T) *Envelope[T] {
+	return &Envelope[T]{Value: value}
+}
+
+// envelope is implemented by all envelopes regardless of the type of the value they carry.
+type envelope interface {
+	header() *envelopeHeader
+}
+
+func (e *Envelope[T]) header() *envelopeHeader {
+	if e == nil {
+		return nil
+	}
+	return &e.envelopeHeader
+}
+
+// headerOf returns the header of the token if it is an envelope and nil otherwise.
+func headerOf[I any](token I) *envelopeHeader {
+	if e, ok := any(token).(envelope); ok {
+		return e.header()
+	}
+	return nil
+}
+
+// envelopeHeader is the metadata carried by the envelope.
+type envelopeHeader struct {
+
+	// id is the unique id of the token assigned on ingestion, or once it is derived from another token.
+	id uint64
+
+	// parentID is the id of the token the token is derived from. It is 0 for the fed and aggregated tokens.
+	parentID uint64
+
+	// correlationID is the id of the fed token the token is derived from. It is the id of the token itself for the fed
+	// and aggregated tokens.
+	correlationID uint64
+
+	// ingestedAt is the time the token, or the fed token it is derived from, was fed to the pipeline.
+	ingestedAt time.Time
+
+	// timings is the list of the timings of the steps the token went through.
+	timings []StepTiming
+
+	// attributes is the user defined attributes attached to the token.
+	attributes map[string]string
+
+	// priority is the priority of the token in the input queues of the steps.
+	priority Priority
+
+	// roots is the ids of the tracked fed tokens the token is derived from. Aggregated tokens can have multiple roots.
+	roots []uint64
+
+	// mutex protects the header since a token can be retained by a buffer step while being processed by others.
+	mutex sync.Mutex
+}
+
+// ID returns the unique id of the token. Tokens derived from another token, like fragments, get their own ids.
+func (h *envelopeHeader) ID() uint64 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.id
+}
+
+// ParentID returns the id of the token the token is derived from, or 0 if it is fed or aggregated from multiple tokens.
+func (h *envelopeHeader) ParentID() uint64 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.parentID
+}
+
+// CorrelationID returns the id of the fed token the token is derived from, so that all the tokens derived from the same
+// fed token share the same correlation id. It is the id of the token itself if it is fed or aggregated.
+func (h *envelopeHeader) CorrelationID() uint64 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.correlationID
+}
+
+// IngestedAt returns the time the token, or the fed token it is derived from, was fed to the pipeline.
+func (h *envelopeHeader) IngestedAt() time.Time {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.ingestedAt
+}
+
+// Timings returns a copy of the timings recorded by the steps the token went through.
+func (h *envelopeHeader) Timings() []StepTiming {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	timings := make([]StepTiming, len(h.timings))
+	copy(timings, h.timings)
+	return timings
+}
+
+// Attribute returns the value of the attribute with the given key.
+func (h *envelopeHeader) Attribute(key string) (string, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	value, ok := h.attributes[key]
+	return value, ok
+}
+
+// SetAttribute sets the value of the attribute with the given key.
+func (h *envelopeHeader) SetAttribute(key, value string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.attributes == nil {
+		h.attributes = make(map[string]string)
+	}
+	h.attributes[key] = value
+}
+
+// Attributes returns a copy of all the attributes attached to the token.
+func (h *envelopeHeader) Attributes() map[string]string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	attributes := make(map[string]string, len(h.attributes))
+	for k, v := range h.attributes {
+		attributes[k] = v
+	}
+	return attributes
+}
+
+// Priority returns the priority of the token in the input queues of the steps.
+func (h *envelopeHeader) Priority() Priority {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.priority
+}
+
+// SetPriority changes the priority of the token in the input queues of the following steps.
+func (h *envelopeHeader) SetPriority(priority Priority) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.priority = priority
+}
+
+// stamp assigns the id and the ingestion time if they are not already set.
+func (h *envelopeHeader) stamp() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.id != 0 {
+		return
+	}
+	h.stampLocked()
+}
+
+// restamp assigns a new id and ingestion time to the token fed to a pipeline, even if it was fed before.
+// The roots of the previous feed are cleared since they belong to the lineages of that feed.
+func (h *envelopeHeader) restamp() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.stampLocked()
+	h.roots = nil
+}
+
+// stampLocked assigns a new id and ingestion time. The mutex must be held.
+func (h *envelopeHeader) stampLocked() {
+	h.id = envelopeIDs.Add(1)
+	h.parentID = 0
+	h.correlationID = h.id
+	h.ingestedAt = time.Now()
+}
+
+// adopt copies the metadata of the parent into the header if it is not already stamped, and assigns it a new id.
+func (h *envelopeHeader) adopt(parent *envelopeHeader) {
+	if h == parent {
+		return
+	}
+	parent.mutex.Lock()
+	defer parent.mutex.Unlock()
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.id != 0 {
+		return
+	}
+	h.id = envelopeIDs.Add(1)
+	h.parentID = parent.id
+	h.correlationID = parent.correlationID
+	h.ingestedAt = parent.ingestedAt
+	h.priority = parent.priority
+	h.roots = append([]uint64(nil), parent.roots...)
+	h.timings = append([]StepTiming(nil), parent.timings...)
+	if parent.attributes != nil {
+		h.attributes = make(map[string]string, len(parent.attributes))
+		for k, v := range parent.attributes {
+			h.attributes[k] = v
+		}
+	}
+}
+
+// getRoots returns the ids of the tracked fed tokens the token is derived from.
+func (h *envelopeHeader) getRoots() []uint64 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.roots
+}
+
+// setRoots replaces the ids of the tracked fed tokens the token is derived from.
+func (h *envelopeHeader) setRoots(roots []uint64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.roots = roots
+}
+
+// addTiming records the time taken by a step to process the token.
+func (h *envelopeHeader) addTiming(label string, start time.Time) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.timings = append(h.timings, StepTiming{Label: label, Start: start, Duration: time.Since(start)})
+}
+
+// stampEnvelope stamps the token if it is an envelope.
+func stampEnvelope[I any](token I) {
+	if h := headerOf(token); h != nil {
+		h.stamp()
+	}
+}
+
+// restampEnvelope restamps the token fed to a pipeline if it is an envelope.
+func restampEnvelope[I any](token I) {
+	if h := headerOf(token); h != nil {
+		h.restamp()
+	}
+}
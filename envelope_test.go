@@ -0,0 +1,173 @@
+package pipelines
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEnvelope_Attributes(t *testing.T) {
+	e := NewEnvelope(5)
+
+	if _, ok := e.Attribute("source"); ok {
+		t.Error("expected attribute to be missing")
+	}
+
+	e.SetAttribute("source", "sensor")
+	value, ok := e.Attribute("source")
+	if !ok || value != "sensor" {
+		t.Errorf("expected attribute to be 'sensor', got '%s'", value)
+	}
+
+	attributes := e.Attributes()
+	attributes["source"] = "changed"
+	if value, _ := e.Attribute("source"); value != "sensor" {
+		t.Error("expected attributes to return a copy")
+	}
+}
+
+func TestEnvelope_Stamp(t *testing.T) {
+	e := NewEnvelope(5)
+	if e.ID() != 0 {
+		t.Errorf("expected id to be 0 before stamping, got %d", e.ID())
+	}
+
+	stampEnvelope(e)
+	id := e.ID()
+	if id == 0 {
+		t.Error("expected id to be set after stamping")
+	}
+	if e.IngestedAt().IsZero() {
+		t.Error("expected ingestion time to be set after stamping")
+	}
+
+	stampEnvelope(e)
+	if e.ID() != id {
+		t.Error("expected stamping to be done once")
+	}
+
+	other := NewEnvelope(6)
+	stampEnvelope(other)
+	if other.ID() == id {
+		t.Error("expected ids to be unique")
+	}
+
+	// stamping plain tokens and nil envelopes must be safe.
+	stampEnvelope(5)
+	var nilEnvelope *Envelope[int]
+	stampEnvelope(nilEnvelope)
+}
+
+func TestEnvelope_Restamp(t *testing.T) {
+	builder := &Builder[*Envelope[int]]{}
+	sink := builder.NewStep(StepTerminalConfig[*Envelope[int]]{Process: func(*Envelope[int]) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, sink)
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+
+	// an envelope fed again is stamped as a new token.
+	e := NewEnvelope(1)
+	p.FeedOne(e)
+	id, ingestedAt := e.ID(), e.IngestedAt()
+	time.Sleep(time.Millisecond)
+	p.FeedOne(e)
+	if e.ID() == id || e.CorrelationID() != e.ID() {
+		t.Errorf("expected a new id once fed again, got %d", e.ID())
+	}
+	if !e.IngestedAt().After(ingestedAt) {
+		t.Error("expected a new ingestion time once fed again")
+	}
+}
+
+func TestEnvelope_Adopt(t *testing.T) {
+	parent := NewEnvelope("parent")
+	stampEnvelope(parent)
+	parent.SetAttribute("key", "value")
+	parent.addTiming("step", time.Now())
+
+	child := NewEnvelope("child")
+	child.adopt(&parent.envelopeHeader)
+
+	if child.ID() == 0 || child.ID() == parent.ID() {
+		t.Errorf("expected child to have its own id, got %d", child.ID())
+	}
+	if child.ParentID() != parent.ID() || child.CorrelationID() != parent.ID() {
+		t.Errorf("expected child to be correlated with %d, got parent %d and correlation %d", parent.ID(), child.ParentID(), child.CorrelationID())
+	}
+	if child.IngestedAt() != parent.IngestedAt() {
+		t.Error("expected child to have the ingestion time of the parent")
+	}
+	if value, _ := child.Attribute("key"); value != "value" {
+		t.Errorf("expected child to inherit attributes, got '%s'", value)
+	}
+	if len(child.Timings()) != 1 {
+		t.Errorf("expected child to inherit 1 timing, got %d", len(child.Timings()))
+	}
+
+	child.SetAttribute("key", "changed")
+	if value, _ := parent.Attribute("key"); value != "value" {
+		t.Error("expected child attributes to be independent from the parent")
+	}
+}
+
+func TestEnvelope_Pipeline(t *testing.T) {
+	builder := &Builder[*Envelope[int]]{}
+
+	tag := builder.NewStep(StepBasicConfig[*Envelope[int]]{
+		Label: "tag",
+		Process: func(e *Envelope[int]) *Envelope[int] {
+			e.SetAttribute("tagged", "yes")
+			return e
+		},
+	})
+	split := builder.NewStep(StepFragmenterConfig[*Envelope[int]]{
+		Label: "split",
+		Process: func(e *Envelope[int]) []*Envelope[int] {
+			return []*Envelope[int]{NewEnvelope(e.Value), NewEnvelope(e.Value * 10)}
+		},
+	})
+
+	var results []*Envelope[int]
+	var resultsMutex sync.Mutex
+	collect := builder.NewStep(StepTerminalConfig[*Envelope[int]]{
+		Label: "collect",
+		Process: func(e *Envelope[int]) {
+			resultsMutex.Lock()
+			defer resultsMutex.Unlock()
+			results = append(results, e)
+		},
+	})
+
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, tag, split, collect)
+	p.Init()
+	p.Run(context.Background())
+
+	fed := NewEnvelope(1)
+	p.FeedOne(fed)
+	p.WaitTillDone()
+	p.Terminate()
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].ID() == results[1].ID() {
+		t.Errorf("expected fragments to have their own ids, got %d", results[0].ID())
+	}
+	for _, r := range results {
+		if r.ID() == fed.ID() || r.ParentID() != fed.ID() || r.CorrelationID() != fed.ID() {
+			t.Errorf("expected fragment to be derived from %d, got id %d, parent %d", fed.ID(), r.ID(), r.ParentID())
+		}
+		if value, _ := r.Attribute("tagged"); value != "yes" {
+			t.Error("expected fragment to inherit the attributes")
+		}
+		labels := []string{}
+		for _, timing := range r.Timings() {
+			labels = append(labels, timing.Label)
+		}
+		if len(labels) != 3 || labels[0] != "tag" || labels[1] != "split" || labels[2] != "collect" {
+			t.Errorf("expected timings of 'tag', 'split' and 'collect', got %v", labels)
+		}
+	}
+}
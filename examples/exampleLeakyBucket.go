@@ -6,7 +6,7 @@ import (
 	"os"
 	"os/signal"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -21,39 +21,7 @@ const (
 	BUFFER_LENGTH = 10
 )
 
-type leakyBucket struct {
-	addedTokensCount int
-	mutex            sync.Mutex
-}
-
-func (l *leakyBucket) inputTriggered(buffer []string) (string, pip.BufferFlags) {
-	l.mutex.Lock()
-	defer l.mutex.Unlock()
-	if l.addedTokensCount < len(buffer) {
-		l.addedTokensCount++
-	}
-	return "", pip.BufferFlags{}
-}
-
-func (l *leakyBucket) timeTriggeredProcess(buffer []string) (string, pip.BufferFlags) {
-	l.mutex.Lock()
-	defer l.mutex.Unlock()
-
-	itemIndex := len(buffer) - l.addedTokensCount
-
-	if l.addedTokensCount > 0 {
-		l.addedTokensCount--
-	}
-
-	if itemIndex < len(buffer) {
-		return buffer[itemIndex], pip.BufferFlags{SendProcessOuput: true}
-	} else {
-		return "----", pip.BufferFlags{SendProcessOuput: true}
-	}
-
-}
-
-func createLeakyBucketPipeline() pip.IPipeline[string] {
+func createLeakyBucketPipeline(printed *atomic.Int64) pip.IPipeline[string] {
 	builder := &pip.Builder[string]{}
 
 	wordSplitter := builder.NewStep(pip.StepFragmenterConfig[string]{
@@ -77,18 +45,23 @@ func createLeakyBucketPipeline() pip.IPipeline[string] {
 		},
 	})
 
-	leakyBucket := &leakyBucket{}
-	bucketStep := builder.NewStep(pip.StepBufferConfig[string]{
-		Label:                        "Leaky Bucket Step",
-		BufferSize:                   BUFFER_LENGTH,
-		InputTriggeredProcess:        leakyBucket.inputTriggered,
-		TimeTriggeredProcess:         leakyBucket.timeTriggeredProcess,
-		TimeTriggeredProcessInterval: 1 * time.Second,
+	// the words leave the bucket 1 per second, and the words overflowing the bucket are reported as dropped.
+	bucketStep := builder.NewStep(pip.StepRateLimiterConfig[string]{
+		Label:     "Leaky Bucket Step",
+		Replicas:  1,
+		Rate:      1,
+		Burst:     BUFFER_LENGTH,
+		Algorithm: pip.LeakyBucket,
+		Excess:    pip.RateLimitDivert,
+		Divert: func(in string) {
+			fmt.Println("dropped:", in)
+		},
 	})
 
 	printer := builder.NewStep(pip.StepTerminalConfig[string]{
 		Label: "terminal",
 		Process: func(in string) {
+			printed.Store(time.Now().UnixNano())
 			fmt.Println(in)
 		},
 	})
@@ -109,19 +82,35 @@ func ExampleLeakyBucket() {
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	ctx, cancelCtx := context.WithCancel(context.Background())
 
-	pipeline := createLeakyBucketPipeline()
+	var printed atomic.Int64
+	pipeline := createLeakyBucketPipeline(&printed)
 	pipeline.Run(ctx)
 
 	go func() {
+		// the first 3 texts arrive at once, so the words overflowing the bucket are dropped.
 		pipeline.FeedOne(TEXT)
-		time.Sleep(2 * time.Second)
 		pipeline.FeedOne(TEXT_2)
-		time.Sleep(15 * time.Second)
 		pipeline.FeedOne(TEXT_3)
-		time.Sleep(15 * time.Second)
+		time.Sleep(20 * time.Second)
 		pipeline.FeedOne(TEXT_4)
 	}()
 
+	// printing "----" when no word leaves the bucket for a second.
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if time.Since(time.Unix(0, printed.Load())) > time.Second {
+					fmt.Println("----")
+				}
+			}
+		}
+	}()
+
 	// Goroutine to cancel the context when an interrupt signal is received
 	go func() {
 		<-sigChan
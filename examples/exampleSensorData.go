@@ -250,18 +250,8 @@ func ExampleSensor() {
 
 	// Simulating the sensor data generation every 50ms
 	go func() {
-		ticker := time.NewTicker(SIMULATED_DATA_GEN_INTERVAL)
-		for {
-			select {
-			case <-ctx.Done():
-				ticker.Stop()
-				fmt.Println("Data Generation Stopped!!!")
-				return
-			case <-ticker.C:
-				data := generateSensorData()
-				pipeline.FeedOne(data)
-			}
-		}
+		pipeline.FeedFrom(ctx, pip.NewTickerSource(SIMULATED_DATA_GEN_INTERVAL, generateSensorData))
+		fmt.Println("Data Generation Stopped!!!")
 	}()
 
 	<-ctx.Done()
@@ -270,7 +260,7 @@ func ExampleSensor() {
 	fmt.Println("Sensor Data Example Done!!!")
 }
 
-func generateSensorData() *SensorData {
+func generateSensorData(time.Time) *SensorData {
 	return &SensorData{
 		TemperatureValue:      rand.Float64()*(TEMPERATURE_VALID_VALUE_MAX-TEMPERATURE_VALID_VALUE_MIN) + TEMPERATURE_VALID_VALUE_MIN,
 		TemperatureErrorValue: rand.Float64() * 5, // Example error value
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package pipelines
+
+import "os"
+
+// fileID returns 0 since the identity of the file is not available on this platform.
+func fileID(os.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package pipelines
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileID returns the inode number of the file, which identifies it even if it is renamed.
+func fileID(info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino)
+	}
+	return 0
+}
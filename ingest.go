@@ -0,0 +1,147 @@
+package pipelines
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// defaultIngestionMaxBodySize is the max size of the request body accepted by the ingestion handler if it is not set.
+const defaultIngestionMaxBodySize = 1 << 20
+
+// IngestionHandlerConfig is a struct that defines the configuration for the ingestion handler.
+type IngestionHandlerConfig[I any] struct {
+
+	// Decoder decodes every JSON value of the payload into a token. It is NewJSONCodec if it is not set.
+	Decoder Decoder[I]
+
+	// MaxBodySize is the max size of the request body in bytes. It is 1MiB if it is not set.
+	MaxBodySize int64
+}
+
+// IngestionResponse is the JSON body of the responses of the ingestion handler.
+type IngestionResponse struct {
+
+	// Accepted is the number of the tokens of the payload fed to the pipeline. They are the first tokens of the payload.
+	Accepted int `json:"accepted"`
+
+	// Error is the reason the rest of the tokens are not fed. It is empty if all the tokens are fed.
+	Error string `json:"error,omitempty"`
+}
+
+// ingestionHandler feeds the tokens received over HTTP to a pipeline.
+type ingestionHandler[I any] struct {
+	pipeline    IPipeline[I]
+	decoder     Decoder[I]
+	maxBodySize int64
+}
+
+// NewIngestionHandler creates an http.Handler feeding the JSON payloads posted to it to the pipeline. The payload is either
+// a single JSON value or an array of values, each decoded into a token. The tokens are fed without blocking, and the handler
+// responds with:
+//
+//   - 202 once all the tokens are fed.
+//   - 400 if the payload can't be decoded, in which case no tokens are fed.
+//   - 413 if the payload is larger than the max body size.
+//   - 429 if the first step can't accept more tokens, with the number of tokens already fed.
+//   - 503 if the pipeline is not accepting tokens, like when it is draining or terminated.
+func NewIngestionHandler[I any](p IPipeline[I], config IngestionHandlerConfig[I]) http.Handler {
+	if p == nil {
+		panic("pipeline is required")
+	}
+	if config.MaxBodySize < 0 {
+		panic("max body size can't be negative")
+	}
+	if config.Decoder == nil {
+		config.Decoder = NewJSONCodec[I]()
+	}
+	if config.MaxBodySize == 0 {
+		config.MaxBodySize = defaultIngestionMaxBodySize
+	}
+	return &ingestionHandler[I]{pipeline: p, decoder: config.Decoder, maxBodySize: config.MaxBodySize}
+}
+
+func (h *ingestionHandler[I]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if state := h.pipeline.State(); state == StateDraining || state == StateTerminated {
+		respondIngestion(w, http.StatusServiceUnavailable, 0, &StateError{Op: "feed", State: state})
+		return
+	}
+
+	tokens, err := h.decode(http.MaxBytesReader(w, r.Body, h.maxBodySize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondIngestion(w, http.StatusRequestEntityTooLarge, 0, err)
+		} else {
+			respondIngestion(w, http.StatusBadRequest, 0, err)
+		}
+		return
+	}
+
+	for accepted, token := range tokens {
+		err := h.pipeline.TryFeed(token)
+		switch {
+		case err == nil:
+			continue
+		case errors.Is(err, ErrPipelineFull):
+			w.Header().Set("Retry-After", "1")
+			respondIngestion(w, http.StatusTooManyRequests, accepted, err)
+		case errors.Is(err, ErrInvalidState):
+			respondIngestion(w, http.StatusServiceUnavailable, accepted, err)
+		default:
+			respondIngestion(w, http.StatusInternalServerError, accepted, err)
+		}
+		return
+	}
+	respondIngestion(w, http.StatusAccepted, len(tokens), nil)
+}
+
+// decode decodes the tokens of the payload, which is either a single JSON value or an array of values.
+func (h *ingestionHandler[I]) decode(body io.Reader) ([]I, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, errors.New("empty payload")
+	}
+
+	values := []json.RawMessage{data}
+	if data[0] == '[' {
+		if err := json.Unmarshal(data, &values); err != nil {
+			return nil, err
+		}
+	}
+	tokens := make([]I, 0, len(values))
+	for i, value := range values {
+		token, err := h.decoder.Decode(value)
+		if errors.Is(err, ErrSkipRecord) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decoding item %d: %w", i, err)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// respondIngestion writes the response of the ingestion handler with the number of the accepted tokens.
+func respondIngestion(w http.ResponseWriter, status int, accepted int, err error) {
+	response := IngestionResponse{Accepted: accepted}
+	if err != nil {
+		response.Error = err.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
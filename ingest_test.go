@@ -0,0 +1,138 @@
+package pipelines
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type ingestedEvent struct {
+	Value int `json:"value"`
+}
+
+// postIngestion posts the payload to the handler and returns the status code and the decoded response.
+func postIngestion(t *testing.T, handler http.Handler, payload string) (int, IngestionResponse) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/ingest", strings.NewReader(payload)))
+	var response IngestionResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode the response: %v", err)
+	}
+	return recorder.Code, response
+}
+
+func TestIngestionHandler(t *testing.T) {
+	var sum atomic.Int64
+	builder := &Builder[ingestedEvent]{}
+	sink := builder.NewStep(StepTerminalConfig[ingestedEvent]{Process: func(e ingestedEvent) { sum.Add(int64(e.Value)) }})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, sink)
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+	handler := NewIngestionHandler(p, IngestionHandlerConfig[ingestedEvent]{})
+
+	if code, response := postIngestion(t, handler, `{"value": 1}`); code != http.StatusAccepted || response.Accepted != 1 {
+		t.Errorf("expected a single token to be accepted, got %d %+v", code, response)
+	}
+	if code, response := postIngestion(t, handler, ` [{"value": 2}, {"value": 3}] `); code != http.StatusAccepted || response.Accepted != 2 {
+		t.Errorf("expected a batch of 2 tokens to be accepted, got %d %+v", code, response)
+	}
+	p.WaitTillDone()
+	if sum.Load() != 6 {
+		t.Errorf("expected sum to be 6, got %d", sum.Load())
+	}
+
+	// no tokens of an invalid batch are fed.
+	for _, payload := range []string{`[{"value": 4}, {"value": "x"}]`, `{"value":`, ``} {
+		if code, response := postIngestion(t, handler, payload); code != http.StatusBadRequest || response.Accepted != 0 || response.Error == "" {
+			t.Errorf("expected %q to be rejected, got %d %+v", payload, code, response)
+		}
+	}
+	p.WaitTillDone()
+	if sum.Load() != 6 {
+		t.Errorf("expected sum to stay 6, got %d", sum.Load())
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/ingest", nil))
+	if recorder.Code != http.StatusMethodNotAllowed || recorder.Header().Get("Allow") != "POST" {
+		t.Errorf("expected GET to be not allowed, got %d", recorder.Code)
+	}
+}
+
+func TestIngestionHandler_MaxBodySize(t *testing.T) {
+	var sum atomic.Int64
+	builder := &Builder[ingestedEvent]{}
+	sink := builder.NewStep(StepTerminalConfig[ingestedEvent]{Process: func(e ingestedEvent) { sum.Add(int64(e.Value)) }})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, sink)
+	p.Init()
+	handler := NewIngestionHandler(p, IngestionHandlerConfig[ingestedEvent]{MaxBodySize: 16})
+
+	if code, _ := postIngestion(t, handler, `[{"value": 1}, {"value": 2}]`); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status 413, got %d", code)
+	}
+}
+
+func TestIngestionHandler_Backpressure(t *testing.T) {
+	var sum atomic.Int64
+	builder := &Builder[ingestedEvent]{}
+	sink := builder.NewStep(StepTerminalConfig[ingestedEvent]{Process: func(e ingestedEvent) { sum.Add(int64(e.Value)) }})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 2, TrackTokensCount: true}, sink)
+	// the pipeline is not running, so the tokens stay in the input channel of the first step.
+	p.Init()
+	handler := NewIngestionHandler(p, IngestionHandlerConfig[ingestedEvent]{})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/ingest", strings.NewReader(`[{"value": 1}, {"value": 2}, {"value": 3}]`)))
+	if recorder.Code != http.StatusTooManyRequests || recorder.Header().Get("Retry-After") == "" {
+		t.Errorf("expected status 429 with Retry-After, got %d", recorder.Code)
+	}
+	var response IngestionResponse
+	json.NewDecoder(recorder.Body).Decode(&response)
+	if response.Accepted != 2 {
+		t.Errorf("expected the first 2 tokens to be accepted, got %+v", response)
+	}
+
+	p.Run(context.Background())
+	p.WaitTillDone()
+	p.Terminate()
+	if code, response := postIngestion(t, handler, `{"value": 4}`); code != http.StatusServiceUnavailable || response.Accepted != 0 {
+		t.Errorf("expected status 503 once terminated, got %d %+v", code, response)
+	}
+	if sum.Load() != 3 {
+		t.Errorf("expected sum to be 3, got %d", sum.Load())
+	}
+}
+
+func TestIngestionHandler_InputQueue(t *testing.T) {
+	var sum atomic.Int64
+	builder := &Builder[ingestedEvent]{}
+	sink := builder.NewStep(StepTerminalConfig[ingestedEvent]{
+		Process:     func(e ingestedEvent) { sum.Add(int64(e.Value)) },
+		StepOptions: StepOptions[ingestedEvent]{InputQueue: &InputQueueConfig[ingestedEvent]{}},
+	})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 2, TrackTokensCount: true}, sink)
+	// the pipeline is not running, so the tokens stay in the input queue of the first step.
+	p.Init()
+	handler := NewIngestionHandler(p, IngestionHandlerConfig[ingestedEvent]{})
+
+	if code, response := postIngestion(t, handler, `[{"value": 1}, {"value": 2}, {"value": 3}]`); code != http.StatusTooManyRequests || response.Accepted != 2 {
+		t.Errorf("expected the 2 tokens fitting the queue to be accepted, got %d %+v", code, response)
+	}
+
+	p.Run(context.Background())
+	defer p.Terminate()
+	p.WaitTillDone()
+	if code, response := postIngestion(t, handler, `[{"value": 4}, {"value": 5}]`); code != http.StatusAccepted || response.Accepted != 2 {
+		t.Errorf("expected the tokens to be accepted while the queue has room, got %d %+v", code, response)
+	}
+	p.WaitTillDone()
+	if sum.Load() != 12 {
+		t.Errorf("expected sum to be 12, got %d", sum.Load())
+	}
+}
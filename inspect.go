@@ -0,0 +1,186 @@
+package pipelines
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PipelineSnapshot is a view of a running pipeline served by the inspection handler.
+type PipelineSnapshot struct {
+
+	// State is the current lifecycle state of the pipeline.
+	State PipelineState `json:"state"`
+
+	// TokensCount is the number of tokens being processed by the pipeline.
+	TokensCount uint64 `json:"tokensCount"`
+
+	// Steps is the list of the steps in the order of execution.
+	Steps []StepSnapshot `json:"steps"`
+
+	// RecentErrors is the list of the most recent errors reported by the pipeline.
+	RecentErrors []ErrorSnapshot `json:"recentErrors"`
+}
+
+// StepSnapshot is a view of a single step of a running pipeline.
+type StepSnapshot struct {
+
+	// Label is the label of the step set by the user.
+	Label string `json:"label"`
+
+	// Type is the type of the step (basic, filter, fragmenter, terminal, buffer, rate limiter, writer or custom).
+	Type string `json:"type"`
+
+	// Replicas is the number of replicas running the step.
+	Replicas uint16 `json:"replicas"`
+
+	// QueueLength is the number of tokens waiting in the input channel or the input queue of the step.
+	QueueLength int `json:"queueLength"`
+
+	// QueueCapacity is the capacity of the input channel or the input queue of the step.
+	QueueCapacity int `json:"queueCapacity"`
+
+	// QueueUtilization is the ratio of the queue length to the queue capacity, between 0 and 1.
+	QueueUtilization float64 `json:"queueUtilization"`
+
+	// Buffered is the number of tokens retained in the buffer and is set for buffer steps only.
+	Buffered *int `json:"buffered,omitempty"`
+
+	// Spilled is the number of tokens spilled to disk by the input queue of the step.
+	Spilled int `json:"spilled"`
+
+	// Dropped is the number of tokens dropped by the overflow policy of the input queue of the step.
+	Dropped uint64 `json:"dropped"`
+
+	// Diverted is the number of tokens diverted by the overflow policy of the input queue of the step.
+	Diverted uint64 `json:"diverted"`
+}
+
+// ErrorSnapshot is a view of an error reported by the pipeline.
+type ErrorSnapshot struct {
+
+	// Time is the time at which the error was reported.
+	Time time.Time `json:"time"`
+
+	// Step is the label of the step which reported the error, and is empty for errors of the pipeline itself.
+	Step string `json:"step,omitempty"`
+
+	// Error is the message of the error.
+	Error string `json:"error"`
+}
+
+// Snapshot takes a snapshot of the current state of the pipeline.
+func Snapshot[I any](p IPipeline[I]) PipelineSnapshot {
+	description := p.Describe()
+	snapshot := PipelineSnapshot{
+		State:        p.State(),
+		TokensCount:  p.TokensCount(),
+		Steps:        make([]StepSnapshot, len(description.Steps)),
+		RecentErrors: []ErrorSnapshot{},
+	}
+	for i, step := range description.Steps {
+		edge := description.Edges[i]
+		snapshot.Steps[i] = StepSnapshot{
+			Label:         step.Label,
+			Type:          step.Type,
+			Replicas:      step.Replicas,
+			QueueLength:   edge.Length,
+			QueueCapacity: edge.Capacity,
+			Spilled:       step.Spilled,
+			Dropped:       step.Dropped,
+			Diverted:      step.Diverted,
+		}
+		if edge.Capacity > 0 {
+			snapshot.Steps[i].QueueUtilization = float64(edge.Length) / float64(edge.Capacity)
+		}
+		if step.Buffer != nil {
+			buffered := step.Buffer.Buffered
+			snapshot.Steps[i].Buffered = &buffered
+		}
+	}
+	for _, err := range p.RecentErrors() {
+		snapshot.RecentErrors = append(snapshot.RecentErrors, ErrorSnapshot{Time: err.Time, Step: err.Step, Error: err.Err.Error()})
+	}
+	return snapshot
+}
+
+// inspectionHandler serves the snapshot of a pipeline over HTTP.
+type inspectionHandler[I any] struct {
+	pipeline IPipeline[I]
+}
+
+// errorReportingPipeline is implemented by the pipelines which collect the errors reported to them.
+type errorReportingPipeline interface {
+	reportError(step string, err error)
+}
+
+// NewInspectionHandler creates an http.Handler serving a view of the pipeline.
+// The view is served as JSON if the request has the query "format=json" or accepts "application/json", and as an HTML page otherwise.
+// Errors writing the view are reported to the pipeline.
+func NewInspectionHandler[I any](p IPipeline[I]) http.Handler {
+	if p == nil {
+		panic("pipeline is required")
+	}
+	return &inspectionHandler[I]{pipeline: p}
+}
+
+func (h *inspectionHandler[I]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	snapshot := Snapshot(h.pipeline)
+
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(snapshot); err != nil {
+			h.reportError(fmt.Errorf("encoding inspection view: %w", err))
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := inspectionPage.Execute(w, snapshot); err != nil {
+		h.reportError(fmt.Errorf("rendering inspection page: %w", err))
+	}
+}
+
+func (h *inspectionHandler[I]) reportError(err error) {
+	if reporter, ok := h.pipeline.(errorReportingPipeline); ok {
+		reporter.reportError("", err)
+	}
+}
+
+var inspectionPage = template.Must(template.New("pipeline").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="5">
+<title>Pipeline</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; text-align: left; }
+</style>
+</head>
+<body>
+<h1>Pipeline</h1>
+<p>State: <b>{{.State}}</b> &middot; Tokens: <b>{{.TokensCount}}</b> &middot; <a href="?format=json">JSON</a></p>
+<h2>Steps</h2>
+<table>
+<tr><th>#</th><th>Label</th><th>Type</th><th>Replicas</th><th>Queue</th><th>Buffered</th><th>Spilled</th><th>Dropped</th><th>Diverted</th></tr>
+{{range $i, $s := .Steps}}<tr><td>{{$i}}</td><td>{{$s.Label}}</td><td>{{$s.Type}}</td><td>{{$s.Replicas}}</td><td>{{$s.QueueLength}}/{{$s.QueueCapacity}}</td><td>{{if $s.Buffered}}{{$s.Buffered}}{{end}}</td><td>{{$s.Spilled}}</td><td>{{$s.Dropped}}</td><td>{{$s.Diverted}}</td></tr>
+{{end}}</table>
+<h2>Recent Errors</h2>
+{{if .RecentErrors}}<table>
+<tr><th>Time</th><th>Step</th><th>Error</th></tr>
+{{range .RecentErrors}}<tr><td>{{.Time.Format "2006-01-02 15:04:05.000"}}</td><td>{{.Step}}</td><td>{{.Error}}</td></tr>
+{{end}}</table>{{else}}<p>None</p>{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,212 @@
+package pipelines
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	builder := &Builder[int]{}
+	double := builder.NewStep(StepBasicConfig[int]{
+		Label:    "double",
+		Replicas: 2,
+		Process:  func(i int) int { return i * 2 },
+	})
+	buffer := builder.NewStep(StepBufferConfig[int]{
+		Label:                        "buffer",
+		InputChannelSize:             5,
+		BufferSize:                   3,
+		PassThrough:                  true,
+		TimeTriggeredProcess:         func([]int) (int, BufferFlags) { return 0, BufferFlags{} },
+		TimeTriggeredProcessInterval: time.Second,
+	})
+	printStep := builder.NewStep(StepTerminalConfig[int]{
+		Process: func(int) {},
+	})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, double, buffer, printStep)
+	p.Init()
+	p.FeedMany([]int{1, 2})
+
+	snapshot := Snapshot[int](p)
+
+	if snapshot.State != StateInitialized {
+		t.Errorf("expected state to be initialized, got %s", snapshot.State)
+	}
+	if snapshot.TokensCount != 2 {
+		t.Errorf("expected tokens count to be 2, got %d", snapshot.TokensCount)
+	}
+	if len(snapshot.Steps) != 3 {
+		t.Fatalf("expected 3 steps, got %d", len(snapshot.Steps))
+	}
+	first := snapshot.Steps[0]
+	if first.QueueLength != 2 || first.QueueCapacity != 10 || first.QueueUtilization != 0.2 {
+		t.Errorf("unexpected queue of the first step: %+v", first)
+	}
+	if snapshot.Steps[1].Buffered == nil || *snapshot.Steps[1].Buffered != 0 {
+		t.Error("expected buffered count to be set for buffer step")
+	}
+	if snapshot.Steps[0].Buffered != nil {
+		t.Error("expected buffered count to be nil for basic step")
+	}
+}
+
+func TestInspectionHandler_JSON(t *testing.T) {
+	builder := &Builder[int]{}
+	double := builder.NewStep(StepBasicConfig[int]{
+		Label:    "double",
+		Replicas: 2,
+		Process:  func(i int) int { return i * 2 },
+	})
+	buffer := builder.NewStep(StepBufferConfig[int]{
+		Label:                        "buffer",
+		InputChannelSize:             5,
+		BufferSize:                   3,
+		PassThrough:                  true,
+		TimeTriggeredProcess:         func([]int) (int, BufferFlags) { return 0, BufferFlags{} },
+		TimeTriggeredProcessInterval: time.Second,
+	})
+	printStep := builder.NewStep(StepTerminalConfig[int]{
+		Process: func(int) {},
+	})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, double, buffer, printStep).(*pipeline[int])
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+	p.reportError("double", &StateError{Op: "feed", State: StateTerminated})
+
+	handler := NewInspectionHandler[int](p)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/?format=json", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", recorder.Code)
+	}
+	if recorder.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("expected JSON content type, got %s", recorder.Header().Get("Content-Type"))
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("expected valid JSON, got error: %v", err)
+	}
+	if body["state"] != "running" {
+		t.Errorf("expected state to be running, got %v", body["state"])
+	}
+	errs := body["recentErrors"].([]any)
+	if len(errs) != 1 || errs[0].(map[string]any)["step"] != "double" {
+		t.Errorf("expected 1 error reported by 'double', got %v", errs)
+	}
+}
+
+func TestInspectionHandler_HTML(t *testing.T) {
+	builder := &Builder[int]{}
+	double := builder.NewStep(StepBasicConfig[int]{
+		Label:    "double",
+		Replicas: 2,
+		Process:  func(i int) int { return i * 2 },
+	})
+	buffer := builder.NewStep(StepBufferConfig[int]{
+		Label:                        "buffer",
+		InputChannelSize:             5,
+		BufferSize:                   3,
+		PassThrough:                  true,
+		TimeTriggeredProcess:         func([]int) (int, BufferFlags) { return 0, BufferFlags{} },
+		TimeTriggeredProcessInterval: time.Second,
+	})
+	printStep := builder.NewStep(StepTerminalConfig[int]{
+		Process: func(int) {},
+	})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, double, buffer, printStep)
+	p.Init()
+
+	handler := NewInspectionHandler[int](p)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("expected HTML content type, got %s", recorder.Header().Get("Content-Type"))
+	}
+	body := recorder.Body.String()
+	for _, expected := range []string{"initialized", "double", "<td>0/5</td><td>0</td>"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected page to contain %q", expected)
+		}
+	}
+}
+
+func TestInspectionHandler_MethodNotAllowed(t *testing.T) {
+	builder := &Builder[int]{}
+	double := builder.NewStep(StepBasicConfig[int]{
+		Label:    "double",
+		Replicas: 2,
+		Process:  func(i int) int { return i * 2 },
+	})
+	buffer := builder.NewStep(StepBufferConfig[int]{
+		Label:                        "buffer",
+		InputChannelSize:             5,
+		BufferSize:                   3,
+		PassThrough:                  true,
+		TimeTriggeredProcess:         func([]int) (int, BufferFlags) { return 0, BufferFlags{} },
+		TimeTriggeredProcessInterval: time.Second,
+	})
+	printStep := builder.NewStep(StepTerminalConfig[int]{
+		Process: func(int) {},
+	})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, double, buffer, printStep)
+	handler := NewInspectionHandler[int](p)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", recorder.Code)
+	}
+}
+
+type failingResponseWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w failingResponseWriter) Write([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestInspectionHandler_WriteError(t *testing.T) {
+	builder := &Builder[int]{}
+	double := builder.NewStep(StepBasicConfig[int]{
+		Label:    "double",
+		Replicas: 2,
+		Process:  func(i int) int { return i * 2 },
+	})
+	buffer := builder.NewStep(StepBufferConfig[int]{
+		Label:                        "buffer",
+		InputChannelSize:             5,
+		BufferSize:                   3,
+		PassThrough:                  true,
+		TimeTriggeredProcess:         func([]int) (int, BufferFlags) { return 0, BufferFlags{} },
+		TimeTriggeredProcessInterval: time.Second,
+	})
+	printStep := builder.NewStep(StepTerminalConfig[int]{
+		Process: func(int) {},
+	})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, double, buffer, printStep)
+	p.Init()
+
+	handler := NewInspectionHandler[int](p)
+	handler.ServeHTTP(failingResponseWriter{httptest.NewRecorder()}, httptest.NewRequest(http.MethodGet, "/?format=json", nil))
+	handler.ServeHTTP(failingResponseWriter{httptest.NewRecorder()}, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	errs := p.RecentErrors()
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 reported errors, got %v", errs)
+	}
+	for _, err := range errs {
+		if !strings.Contains(err.Err.Error(), "connection reset") {
+			t.Errorf("expected write error to be reported, got %v", err.Err)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrTokenAbandoned is reported by the token handles when the pipeline is terminated or reset before the token is done.
+var ErrTokenAbandoned = errors.New("token abandoned before being done")
+
+// tokenOutcome is the way a token derived from a fed token left the pipeline.
+type tokenOutcome int
+
+const (
+	// outcomeCompleted is the outcome of the tokens processed by a terminal step.
+	outcomeCompleted tokenOutcome = iota
+
+	// outcomeFiltered is the outcome of the tokens filtered out or fragmented into nothing.
+	outcomeFiltered
+
+	// outcomeDropped is the outcome of the tokens dropped or diverted by a queue or a rate limiter, evicted from a buffer,
+	// or failed to be written by a writer step.
+	outcomeDropped
+
+	// outcomeDerived is the outcome of the tokens replaced by the tokens derived from them, like fragmented or flushed tokens.
+	outcomeDerived
+)
+
+// TokenOutcome is the number of the tokens derived from a fed token which left the pipeline in each way.
+// The fed token itself is counted if it reaches the end of the pipeline as is.
+type TokenOutcome struct {
+
+	// Completed is the number of the tokens processed by a terminal step.
+	Completed int
+
+	// Filtered is the number of the tokens filtered out or fragmented into nothing.
+	Filtered int
+
+	// Dropped is the number of the tokens dropped or diverted by input queues or rate limiters, evicted from buffers or
+	// left in them at the end of the input, or failed to be written by writer steps.
+	Dropped int
+}
+
+// TokenHandle is returned by FeedTracked to follow a fed token till all the tokens derived from it leave the pipeline.
+// A token retained by a buffer step is still in the pipeline till the buffer is flushed or the token is evicted from it.
+type TokenHandle struct {
+	done    chan struct{}
+	outcome TokenOutcome
+	err     error
+
+	// result is the first derived token processed by a terminal step. It is nil if there is none.
+	result any
+
+	// awaitsResult is set if the handle is waited by Process, so the derived tokens reaching the end of the pipeline
+	// are taken as results rather than sent to the output of the pipeline.
+	awaitsResult bool
+}
+
+func newTokenHandle() *TokenHandle {
+	return &TokenHandle{done: make(chan struct{})}
+}
+
+// Done returns a channel which is closed once the token is done.
+func (h *TokenHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Wait blocks till the token is done or the context is cancelled, and returns the outcome and the error of the token.
+func (h *TokenHandle) Wait(ctx context.Context) (TokenOutcome, error) {
+	select {
+	case <-h.done:
+		return h.outcome, h.err
+	case <-ctx.Done():
+		return TokenOutcome{}, ctx.Err()
+	}
+}
+
+// Outcome returns the outcome of the token. It is complete only once the token is done.
+func (h *TokenHandle) Outcome() TokenOutcome {
+	select {
+	case <-h.done:
+		return h.outcome
+	default:
+		return TokenOutcome{}
+	}
+}
+
+// Err returns ErrTokenAbandoned if the token was abandoned, and nil if it is done normally or not done yet.
+func (h *TokenHandle) Err() error {
+	select {
+	case <-h.done:
+		return h.err
+	default:
+		return nil
+	}
+}
+
+// complete marks the token as done.
+func (h *TokenHandle) complete(l *lineage, err error) {
+	h.outcome = l.outcome
+	h.result = l.result
+	h.err = err
+	close(h.done)
+}
+
+// lineage tracks the tokens derived from a fed token till they all leave the pipeline.
+type lineage struct {
+
+	// pending is the number of derived tokens which are still in the pipeline.
+	pending int
+
+	// outcome is the number of derived tokens which left the pipeline in each way.
+	outcome TokenOutcome
+
+	// result is the first derived token processed by a terminal step.
+	result any
+
+	// awaitsResult is set if the lineage is followed by Process.
+	awaitsResult bool
+
+	// onDone is called once all the derived tokens left the pipeline, or with an error if the lineage is abandoned.
+	onDone func(*lineage, error)
+}
+
+// lineageRegistry keeps the lineages of the tracked tokens by the root id carried by their envelopes.
+type lineageRegistry struct {
+	lineages map[uint64]*lineage
+
+	// nextRoot is the root id of the next tracked token. The root ids are independent of the envelope ids
+	// since the envelopes can be fed with the ids assigned by another pipeline.
+	nextRoot uint64
+
+	mutex sync.Mutex
+}
+
+func newLineageRegistry() *lineageRegistry {
+	return &lineageRegistry{lineages: make(map[uint64]*lineage)}
+}
+
+// track starts tracking the tokens derived from the fed envelope.
+func (r *lineageRegistry) track(h *envelopeHeader, awaitsResult bool, onDone func(*lineage, error)) {
+	r.mutex.Lock()
+	r.nextRoot++
+	root := r.nextRoot
+	r.lineages[root] = &lineage{pending: 1, awaitsResult: awaitsResult, onDone: onDone}
+	r.mutex.Unlock()
+	h.setRoots([]uint64{root})
+}
+
+// abandon stops tracking all the lineages, and calls their callbacks with the error.
+// It is used when the tokens in the pipeline are discarded.
+func (r *lineageRegistry) abandon(err error) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	lineages := r.lineages
+	r.lineages = make(map[uint64]*lineage)
+	r.mutex.Unlock()
+
+	for _, l := range lineages {
+		l.onDone(l, err)
+	}
+}
+
+// add counts a new token derived from the given roots.
+func (r *lineageRegistry) add(roots []uint64) {
+	if r == nil || len(roots) == 0 {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, root := range roots {
+		if l, ok := r.lineages[root]; ok {
+			l.pending++
+		}
+	}
+}
+
+// release counts a token derived from the given roots leaving the pipeline with the given outcome.
+func (r *lineageRegistry) release(roots []uint64, outcome tokenOutcome) {
+	if r == nil || len(roots) == 0 {
+		return
+	}
+	var done []*lineage
+	r.mutex.Lock()
+	for _, root := range roots {
+		l, ok := r.lineages[root]
+		if !ok {
+			continue
+		}
+		switch outcome {
+		case outcomeCompleted:
+			l.outcome.Completed++
+		case outcomeFiltered:
+			l.outcome.Filtered++
+		case outcomeDropped:
+			l.outcome.Dropped++
+		}
+		l.pending--
+		if l.pending == 0 {
+			delete(r.lineages, root)
+			done = append(done, l)
+		}
+	}
+	r.mutex.Unlock()
+
+	// the callbacks are called outside the lock since they can feed the pipeline.
+	for _, l := range done {
+		l.onDone(l, nil)
+	}
+}
+
+// complete counts a token derived from the given roots processed by a terminal step, and keeps it as the result
+// of the lineages which have no result yet.
+func (r *lineageRegistry) complete(roots []uint64, token any) {
+	if r == nil || len(roots) == 0 {
+		return
+	}
+	r.mutex.Lock()
+	for _, root := range roots {
+		if l, ok := r.lineages[root]; ok && l.result == nil {
+			l.result = token
+		}
+	}
+	r.mutex.Unlock()
+	r.release(roots, outcomeCompleted)
+}
+
+// awaitsResult returns true if any of the lineages of the given roots is followed by Process.
+func (r *lineageRegistry) awaitsResult(roots []uint64) bool {
+	if r == nil || len(roots) == 0 {
+		return false
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, root := range roots {
+		if l, ok := r.lineages[root]; ok && l.awaitsResult {
+			return true
+		}
+	}
+	return false
+}
+
+// lineageTracked is implemented by the steps which report the tokens they derive and release to the lineage registry.
+type lineageTracked interface {
+	setLineageRegistry(*lineageRegistry)
+}
+
+func (s *stepBase[I]) setLineageRegistry(registry *lineageRegistry) {
+	s.lineages = registry
+}
+
+// derive counts the child as a new token derived from the parent, and passes the metadata of the parent to the child.
+func (s *stepBase[I]) derive(parent, child I) {
+	s.inherit(parent, child)
+	if h := headerOf(child); h != nil {
+		s.lineages.add(h.getRoots())
+	}
+}
+
+// hold counts another reference to the token, like a copy retained by a buffer step while the token is passed on.
+func (s *stepBase[I]) hold(token I) {
+	if h := headerOf(token); h != nil {
+		s.lineages.add(h.getRoots())
+	}
+}
+
+// release counts the token leaving the pipeline with the given outcome.
+func (s *stepBase[I]) release(token I, outcome tokenOutcome) {
+	if h := headerOf(token); h != nil {
+		s.lineages.release(h.getRoots(), outcome)
+	}
+}
+
+// complete counts the token processed by a terminal step, and passes it as the result of its lineages.
+func (s *stepBase[I]) complete(token I) {
+	if h := headerOf(token); h != nil {
+		s.lineages.complete(h.getRoots(), token)
+	}
+}
+
+// replace passes the lineage of the token to the output of a step processing it one to one.
+// If the output is another stamped token, it keeps its own lineage and the token is counted as replaced by it.
+func (s *stepBase[I]) replace(token, output I) {
+	h := headerOf(token)
+	outputHeader := headerOf(output)
+	if h == nil || outputHeader == nil || h == outputHeader {
+		return
+	}
+	if outputHeader.ID() == 0 {
+		outputHeader.adopt(h)
+		return
+	}
+	s.hold(output)
+	s.release(token, outcomeDerived)
+}
+
+// aggregate counts the output of a buffer process as a new token derived from all the tokens in the buffer.
+// If the output is one of the existing tokens, it is counted as another reference to it instead.
+func (s *stepBase[I]) aggregate(buffer []I, output I) {
+	h := headerOf(output)
+	if h == nil {
+		return
+	}
+	if h.ID() == 0 {
+		seen := make(map[uint64]bool)
+		var roots []uint64
+		for _, token := range buffer {
+			if th := headerOf(token); th != nil {
+				for _, root := range th.getRoots() {
+					if !seen[root] {
+						seen[root] = true
+						roots = append(roots, root)
+					}
+				}
+			}
+		}
+		h.setRoots(roots)
+	}
+	s.lineages.add(h.getRoots())
+}
+
+// trackLineage tracks the tokens derived from the fed token if it is logged or followed by a handle.
+// The logged token is acknowledged once they all leave the pipeline, and the handle is completed.
+func (p *pipeline[I]) trackLineage(token I, seq uint64, handle *TokenHandle) {
+	if p.wal == nil && handle == nil {
+		return
+	}
+	p.lineages.track(headerOf(token), handle != nil && handle.awaitsResult, func(l *lineage, err error) {
+		// the abandoned tokens are not acknowledged so that they are replayed.
+		if p.wal != nil && err == nil {
+			if err := p.wal.ack(seq); err != nil {
+				p.reportError("", fmt.Errorf("acknowledging write-ahead log: %w", err))
+			}
+		}
+		if handle != nil {
+			handle.complete(l, err)
+		}
+	})
+}
+
+func (p *pipeline[I]) FeedTracked(item I) (*TokenHandle, error) {
+	if headerOf(item) == nil {
+		return nil, ErrEnvelopeRequired
+	}
+	handle := newTokenHandle()
+	if err := p.feed(context.Background(), item, handle); err != nil {
+		return nil, err
+	}
+	return handle, nil
+}
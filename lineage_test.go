@@ -0,0 +1,208 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLineageRegistry(t *testing.T) {
+	registry := newLineageRegistry()
+	var done *lineage
+	calls := 0
+	e := NewEnvelope(1)
+	registry.track(&e.envelopeHeader, false, func(l *lineage, err error) {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		done = l
+		calls++
+	})
+	roots := e.getRoots()
+	if len(roots) != 1 {
+		t.Fatalf("expected the envelope to have a root, got %v", roots)
+	}
+
+	// the token is fragmented into two tokens, one of them is filtered and the other is completed.
+	registry.add(roots)
+	registry.add(roots)
+	registry.release(roots, outcomeDerived)
+	registry.release(roots, outcomeFiltered)
+	if calls != 0 {
+		t.Fatalf("expected the lineage to be pending")
+	}
+	registry.release(roots, outcomeCompleted)
+	if calls != 1 {
+		t.Fatalf("expected the lineage to be done once, got %d", calls)
+	}
+	if done.outcome != (TokenOutcome{Completed: 1, Filtered: 1}) {
+		t.Errorf("unexpected outcome: %+v", done.outcome)
+	}
+
+	// the tokens of untracked roots are ignored.
+	registry.release(roots, outcomeCompleted)
+	if calls != 1 {
+		t.Errorf("expected the lineage to be done once, got %d", calls)
+	}
+}
+
+func TestStepBase_Aggregate(t *testing.T) {
+	registry := newLineageRegistry()
+	step := newBaseStep[*Envelope[int]]("buffer", 1, 1)
+	step.setLineageRegistry(registry)
+
+	completed := map[int]bool{}
+	buffer := []*Envelope[int]{NewEnvelope(1), NewEnvelope(2)}
+	for _, e := range buffer {
+		e.stamp()
+		registry.track(&e.envelopeHeader, false, func(*lineage, error) { completed[e.Value] = true })
+	}
+
+	// the aggregated token holds the lineages of all the buffered tokens.
+	output := NewEnvelope(3)
+	step.aggregate(buffer, output)
+	if roots := output.getRoots(); len(roots) != 2 {
+		t.Fatalf("expected the output to have 2 roots, got %v", roots)
+	}
+	for _, e := range buffer {
+		step.release(e, outcomeDerived)
+	}
+	if len(completed) != 0 {
+		t.Fatalf("expected the lineages to be pending, got %v", completed)
+	}
+	step.release(output, outcomeCompleted)
+	if !completed[1] || !completed[2] {
+		t.Errorf("expected both lineages to be done, got %v", completed)
+	}
+}
+
+func TestPipeline_FeedTracked(t *testing.T) {
+	builder := &Builder[*Envelope[int]]{}
+	fragmenter := builder.NewStep(StepFragmenterConfig[*Envelope[int]]{
+		Label: "fragmenter",
+		Process: func(e *Envelope[int]) []*Envelope[int] {
+			return []*Envelope[int]{NewEnvelope(e.Value * 10), NewEnvelope(e.Value*10 + 1), NewEnvelope(e.Value*10 + 2)}
+		},
+	})
+	filter := builder.NewStep(StepFilterConfig[*Envelope[int]]{
+		Label:        "odd",
+		PassCriteria: func(e *Envelope[int]) bool { return e.Value%2 == 1 },
+	})
+	sink := builder.NewStep(StepTerminalConfig[*Envelope[int]]{Label: "sink", Process: func(*Envelope[int]) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, fragmenter, filter, sink)
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+
+	handle, err := p.FeedTracked(NewEnvelope(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	outcome, err := handle.Wait(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if outcome != (TokenOutcome{Completed: 1, Filtered: 2}) {
+		t.Errorf("unexpected outcome: %+v", outcome)
+	}
+}
+
+func TestPipeline_FeedTracked_StampedOutput(t *testing.T) {
+	// the basic step returns an envelope which is already stamped instead of the fed one.
+	cached := NewEnvelope(100)
+	cached.stamp()
+	builder := &Builder[*Envelope[int]]{}
+	lookup := builder.NewStep(StepBasicConfig[*Envelope[int]]{
+		Label:   "lookup",
+		Process: func(*Envelope[int]) *Envelope[int] { return cached },
+	})
+	sink := builder.NewStep(StepTerminalConfig[*Envelope[int]]{Label: "sink", Process: func(*Envelope[int]) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, lookup, sink)
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+
+	handle, err := p.FeedTracked(NewEnvelope(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	// the fed token is replaced by the output, so it has no completed tokens.
+	outcome, err := handle.Wait(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if outcome != (TokenOutcome{}) {
+		t.Errorf("unexpected outcome: %+v", outcome)
+	}
+}
+
+func TestPipeline_FeedTracked_Aggregate(t *testing.T) {
+	builder := &Builder[*Envelope[int]]{}
+	buffer := builder.NewStep(StepBufferConfig[*Envelope[int]]{
+		Label:      "pairs",
+		BufferSize: 2,
+		InputTriggeredProcess: func(buffer []*Envelope[int]) (*Envelope[int], BufferFlags) {
+			if len(buffer) < 2 {
+				return nil, BufferFlags{}
+			}
+			return NewEnvelope(buffer[0].Value + buffer[1].Value), BufferFlags{SendProcessOuput: true, FlushBuffer: true}
+		},
+	})
+	sink := builder.NewStep(StepTerminalConfig[*Envelope[int]]{Label: "sink", Process: func(*Envelope[int]) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, buffer, sink)
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+
+	first, _ := p.FeedTracked(NewEnvelope(1))
+	select {
+	case <-first.Done():
+		t.Fatalf("expected the token to be pending till it is aggregated")
+	case <-time.After(20 * time.Millisecond):
+	}
+	second, _ := p.FeedTracked(NewEnvelope(2))
+	for _, handle := range []*TokenHandle{first, second} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		outcome, err := handle.Wait(ctx)
+		cancel()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if outcome.Completed != 1 {
+			t.Errorf("expected the aggregate to be completed, got %+v", outcome)
+		}
+	}
+}
+
+func TestPipeline_FeedTracked_Abandoned(t *testing.T) {
+	builder := &Builder[*Envelope[int]]{}
+	sink := builder.NewStep(StepTerminalConfig[*Envelope[int]]{Label: "sink", Process: func(*Envelope[int]) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, sink)
+	p.Init()
+
+	// the token fed before running is discarded by the reset.
+	handle, err := p.FeedTracked(NewEnvelope(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p.Reset(false)
+	<-handle.Done()
+	if !errors.Is(handle.Err(), ErrTokenAbandoned) {
+		t.Errorf("expected ErrTokenAbandoned, got %v", handle.Err())
+	}
+}
+
+func TestPipeline_FeedTracked_EnvelopeRequired(t *testing.T) {
+	builder := &Builder[int]{}
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(int) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, sink)
+	p.Init()
+	if _, err := p.FeedTracked(1); !errors.Is(err, ErrEnvelopeRequired) {
+		t.Errorf("expected ErrEnvelopeRequired, got %v", err)
+	}
+}
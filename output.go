@@ -0,0 +1,79 @@
+package pipelines
+
+import (
+	"context"
+	"iter"
+	"sync"
+)
+
+// outputStep is implemented by the steps which pass their tokens on, so they are sent to the output of the pipeline
+// if they are the last step. Any other last step, including the custom steps, is treated as terminal.
+type outputStep interface {
+	sendsOutput()
+}
+
+func (s *stepBasic[I]) sendsOutput() {}
+
+func (s *stepBuffer[I]) sendsOutput() {}
+
+func (s *stepFilter[I]) sendsOutput() {}
+
+func (s *stepFragmenter[I]) sendsOutput() {}
+
+func (s *stepRateLimiter[I]) sendsOutput() {}
+
+func (p *pipeline[I]) Output() <-chan I {
+	p.replicasMutex.Lock()
+	defer p.replicasMutex.Unlock()
+	if p.output == nil {
+		return nil
+	}
+	return p.output
+}
+
+func (p *pipeline[I]) OutputSeq() iter.Seq[I] {
+	output := p.Output()
+	return func(yield func(I) bool) {
+		if output == nil {
+			return
+		}
+		for token := range output {
+			if !yield(token) {
+				return
+			}
+		}
+	}
+}
+
+// forwardOutput passes the tokens sent by the last step to the output of the pipeline till the context is cancelled.
+// The tokens are counted as done once they are received by the consumer, except the tokens derived from the items fed
+// by Process which are done once they reach the output.
+func (p *pipeline[I]) forwardOutput(ctx context.Context, wg *sync.WaitGroup, exit <-chan I, output chan<- I) {
+	defer wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case token := <-exit:
+			// the results of Process are taken by the request, so they are not sent to the output.
+			if h := headerOf(token); h != nil && p.lineages.awaitsResult(h.getRoots()) {
+				p.lineages.complete(h.getRoots(), token)
+				p.decrementTokensCount()
+				continue
+			}
+			p.forwarding.Add(1)
+			select {
+			case output <- token:
+				if h := headerOf(token); h != nil {
+					p.lineages.complete(h.getRoots(), token)
+				}
+				p.decrementTokensCount()
+				p.forwarding.Add(-1)
+			case <-ctx.Done():
+				// the token is discarded like the tokens left in the channels.
+				p.forwarding.Add(-1)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package pipelines
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestPipeline_Output(t *testing.T) {
+	builder := &Builder[int]{}
+	double := builder.NewStep(StepBasicConfig[int]{Label: "double", Process: func(i int) int { return i * 2 }})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, double)
+	if p.Output() != nil {
+		t.Errorf("expected no output before init")
+	}
+	p.Init()
+	p.Run(context.Background())
+	p.FeedMany([]int{1, 2, 3})
+
+	// the tokens are not done till they are received.
+	time.Sleep(20 * time.Millisecond)
+	if p.TokensCount() != 3 {
+		t.Errorf("expected tokens count to be 3, got %d", p.TokensCount())
+	}
+
+	var results []int
+	for range 3 {
+		results = append(results, <-p.Output())
+	}
+	p.WaitTillDone()
+	if p.TokensCount() != 0 {
+		t.Errorf("expected tokens count to be 0, got %d", p.TokensCount())
+	}
+	sort.Ints(results)
+	if results[0] != 2 || results[1] != 4 || results[2] != 6 {
+		t.Errorf("unexpected results: %v", results)
+	}
+
+	p.Terminate()
+	if _, ok := <-p.Output(); ok {
+		t.Errorf("expected output to be closed")
+	}
+}
+
+func TestPipeline_OutputSeq(t *testing.T) {
+	builder := &Builder[int]{}
+	double := builder.NewStep(StepBasicConfig[int]{Label: "double", Process: func(i int) int { return i * 2 }})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, double)
+	p.Init()
+	p.Run(context.Background())
+
+	done := make(chan []int)
+	go func() {
+		var results []int
+		for token := range p.OutputSeq() {
+			results = append(results, token)
+		}
+		done <- results
+	}()
+	p.FeedMany([]int{1, 2, 3})
+	p.WaitTillDone()
+	p.Terminate()
+
+	select {
+	case results := <-done:
+		if len(results) != 3 {
+			t.Errorf("expected 3 results, got %v", results)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected the iteration to stop once the pipeline is terminated")
+	}
+}
+
+func TestPipeline_Output_Process(t *testing.T) {
+	builder := &Builder[*Envelope[int]]{}
+	double := builder.NewStep(StepBasicConfig[*Envelope[int]]{
+		Label:   "double",
+		Process: func(e *Envelope[int]) *Envelope[int] { return NewEnvelope(e.Value * 2) },
+	})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, double)
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+
+	// the result is taken by the request without consuming the output.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	result, err := p.Process(ctx, NewEnvelope(21))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Value != 42 {
+		t.Errorf("expected 42, got %d", result.Value)
+	}
+
+	// the streamed tokens are still sent to the output.
+	p.FeedOne(NewEnvelope(1))
+	select {
+	case token := <-p.Output():
+		if token.Value != 2 {
+			t.Errorf("expected the streamed token 2 to be sent to the output, got %d", token.Value)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the streamed token")
+	}
+}
+
+func TestPipeline_Output_Terminal(t *testing.T) {
+	builder := &Builder[int]{}
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(int) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, sink)
+	p.Init()
+	if p.Output() != nil {
+		t.Errorf("expected no output when the last step is terminal")
+	}
+	for range p.OutputSeq() {
+		t.Errorf("expected no tokens")
+	}
+}
+
+func TestPipeline_Output_CustomStep(t *testing.T) {
+	builder := &Builder[int]{}
+	custom := &mockStep[int]{label: "custom", replicas: 1, finalStep: true}
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, custom)
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+
+	if p.Output() != nil {
+		t.Errorf("expected no output when the last step is custom")
+	}
+	if custom.GetOutputChannel() != nil {
+		t.Errorf("expected the output channel of the custom step not to be set")
+	}
+	p.FeedMany([]int{1, 2, 3})
+	p.WaitTillDone()
+}
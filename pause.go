@@ -0,0 +1,66 @@
+package pipelines
+
+import "context"
+
+func (p *pipeline[I]) Pause() error {
+	p.replicasMutex.Lock()
+
+	if state := p.State(); state != StateRunning {
+		p.replicasMutex.Unlock()
+		return &StateError{Op: "pause", State: state}
+	}
+	p.setState(StatePaused)
+
+	// the replicas are stopped after releasing the lock, so that the pipeline can be described meanwhile.
+	// Resume waits till they are stopped.
+	replicas := p.replicas
+	cancels := make([][]context.CancelFunc, len(replicas))
+	for i, step := range replicas {
+		step.paused = uint16(len(step.cancels))
+		cancels[i] = step.cancels
+		step.cancels = nil
+	}
+	pausing := make(chan struct{})
+	p.pausing = pausing
+	p.replicasMutex.Unlock()
+
+	// pausing the steps from the first to the last, so that the tokens being processed by the replicas of a step
+	// can still be received by the following steps before they are paused.
+	for i, step := range replicas {
+		for _, cancel := range cancels[i] {
+			cancel()
+		}
+		step.running.Wait()
+	}
+
+	p.replicasMutex.Lock()
+	p.pausing = nil
+	p.replicasMutex.Unlock()
+	close(pausing)
+	return nil
+}
+
+func (p *pipeline[I]) Resume() error {
+	p.replicasMutex.Lock()
+	for p.pausing != nil {
+		pausing := p.pausing
+		p.replicasMutex.Unlock()
+		<-pausing
+		p.replicasMutex.Lock()
+	}
+	defer p.replicasMutex.Unlock()
+
+	if state := p.State(); state != StatePaused {
+		return &StateError{Op: "resume", State: state}
+	}
+
+	// running steps in reverse order as done by Run.
+	for i := len(p.replicas) - 1; i >= 0; i-- {
+		for range p.replicas[i].paused {
+			p.spawnReplica(i)
+		}
+		p.replicas[i].paused = 0
+	}
+	p.setState(StateRunning)
+	return nil
+}
@@ -0,0 +1,138 @@
+package pipelines
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPipeline_PauseResume(t *testing.T) {
+	var results atomic.Int64
+	builder := &Builder[int]{}
+	buffer := builder.NewStep(StepBufferConfig[int]{
+		Label:                 "buffer",
+		BufferSize:            10,
+		PassThrough:           true,
+		InputTriggeredProcess: func([]int) (int, BufferFlags) { return 0, BufferFlags{} },
+	})
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(int) { results.Add(1) }})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, buffer, sink)
+	p.Init()
+	p.Run(context.Background())
+
+	p.FeedMany([]int{1, 2})
+	for results.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := p.Pause(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.State() != StatePaused {
+		t.Errorf("expected state to be paused, got %s", p.State())
+	}
+
+	p.FeedMany([]int{3, 4, 5})
+	time.Sleep(50 * time.Millisecond)
+
+	if results.Load() != 2 {
+		t.Errorf("expected no tokens to be processed while paused, got %d", results.Load()-2)
+	}
+	description := p.Describe()
+	if description.Steps[0].QueueLength != 3 {
+		t.Errorf("expected 3 tokens waiting while paused, got %d", description.Steps[0].QueueLength)
+	}
+	if description.Steps[0].Replicas != 0 {
+		t.Errorf("expected no running replicas while paused, got %d", description.Steps[0].Replicas)
+	}
+	if description.Steps[0].Buffer.Buffered != 2 {
+		t.Errorf("expected buffer to be kept while paused, got %d", description.Steps[0].Buffer.Buffered)
+	}
+
+	if err := p.Resume(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.State() != StateRunning {
+		t.Errorf("expected state to be running, got %s", p.State())
+	}
+	for results.Load() < 5 {
+		time.Sleep(time.Millisecond)
+	}
+	if p.Describe().Steps[0].Replicas != 1 {
+		t.Errorf("expected replicas to be restored, got %d", p.Describe().Steps[0].Replicas)
+	}
+	p.Terminate()
+}
+
+func TestPipeline_Pause_DescribeWhileStopping(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	builder := &Builder[int]{}
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(int) {
+		close(started)
+		<-release
+	}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, sink)
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+
+	p.FeedOne(1)
+	<-started
+	paused := make(chan error)
+	go func() { paused <- p.Pause() }()
+	for p.State() != StatePaused {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the replica stopped by Pause is still processing, which doesn't block describing the pipeline.
+	described := make(chan PipelineDescription)
+	go func() { described <- p.Describe() }()
+	select {
+	case description := <-described:
+		if description.Steps[0].Replicas != 0 {
+			t.Errorf("expected no running replicas while pausing, got %d", description.Steps[0].Replicas)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected describe not to wait for the replicas to stop")
+	}
+
+	close(release)
+	if err := <-paused; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := p.Resume(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestPipeline_PauseResume_InvalidState(t *testing.T) {
+	p := &pipeline[int]{
+		steps:              []IStep[int]{&mockStep[int]{replicas: 1}, &mockStep[int]{replicas: 1, finalStep: true}},
+		defaultChannelSize: 10,
+	}
+	p.Init()
+
+	if err := p.Pause(); err == nil {
+		t.Error("expected error pausing a pipeline which is not running")
+	}
+
+	p.Run(context.Background())
+	if err := p.Resume(); err == nil {
+		t.Error("expected error resuming a pipeline which is not paused")
+	}
+
+	if err := p.Pause(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := p.ScaleStep("", 2); err == nil {
+		t.Error("expected error scaling a paused pipeline")
+	}
+
+	// terminating a paused pipeline must not block.
+	p.Terminate()
+	if p.State() != StateTerminated {
+		t.Errorf("expected state to be terminated, got %s", p.State())
+	}
+}
@@ -2,10 +2,20 @@ package pipelines
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"iter"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// ErrTokensCountNotTracked is returned by WaitTillDone when the pipeline is not tracking the tokens count.
+var ErrTokensCountNotTracked = errors.New("tokens count is not tracked by the pipeline")
+
+// ErrPipelineFull is returned by TryFeed if the first step of the pipeline can't accept more tokens without blocking.
+var ErrPipelineFull = errors.New("pipeline is full")
+
 type PipelineConfig struct {
 
 	// DefaultStepInputChannelSize is the buffer size for all channels used to connect steps if the input channel size of any step is not set.
@@ -14,6 +24,15 @@ type PipelineConfig struct {
 	// TrackTokensCount indicates whether the pipeline should keep track of the tokens count or not.
 	// This is required if it is important that all tokens in the pipeline must be processed before termination.
 	TrackTokensCount bool
+
+	// ErrorHandler is called with every error reported by the pipeline or its steps while running. It is optional.
+	ErrorHandler func(PipelineError)
+
+	// Watchdog enables the detection of stuck pipelines if it is set.
+	Watchdog *WatchdogConfig
+
+	// AutoscaleInterval is the interval at which the autoscaling policies of the steps are evaluated. It is set to 1s if it is not set.
+	AutoscaleInterval time.Duration
 }
 
 // IPipeline is an interface that represents a pipeline.
@@ -22,23 +41,100 @@ type IPipeline[I any] interface {
 	// Init initializes the pipeline and has to be called once before running the pipeline.
 	Init() error
 
-	// Run starts the pipeline.
-	Run(ctx context.Context)
+	// Run starts an initialized pipeline.
+	Run(ctx context.Context) error
 
 	// WaitTillDone blocks until all tokens are consumed by the pipeline.
-	WaitTillDone()
+	// It returns ErrTokensCountNotTracked if the pipeline is not tracking the tokens count.
+	WaitTillDone() error
+
+	// Terminate blocks and closes all the channels of a running or paused pipeline.
+	Terminate() error
+
+	// FeedOne feeds a single item to the pipeline. Items can be fed once the pipeline is initialized and till it is terminated.
+	FeedOne(i I) error
+
+	// FeedMany feeds multiple items to the pipeline. It stops at the first item which can't be fed.
+	FeedMany(i []I) error
 
-	// Terminate blocks and closes all the channels
-	Terminate()
+	// TryFeed feeds a single item only if the first step can accept it without blocking, and returns ErrPipelineFull otherwise.
+	// If the first step has an input queue, the item is accepted while the queue has room regardless of the overflow policy.
+	TryFeed(i I) error
 
-	// FeedOne feeds a single item to the pipeline.
-	FeedOne(i I)
+	// FeedFrom feeds the tokens produced by the source till it is exhausted, the context is done, or the pipeline is terminated.
+	// It blocks while the pipeline can't accept more tokens. It returns nil once the source is exhausted, the context error
+	// if it is done first, and a *StateError if the pipeline is terminated first.
+	FeedFrom(ctx context.Context, source Source[I]) error
 
-	// FeedMany feeds multiple items to the pipeline.
-	FeedMany(i []I)
+	// FeedTracked feeds a single item and returns a handle which is done once all the tokens derived from it,
+	// including fragments and aggregates, left the pipeline. It returns ErrEnvelopeRequired if the pipeline is not in envelope mode.
+	FeedTracked(i I) (*TokenHandle, error)
+
+	// Process feeds a single item, waits till all the tokens derived from it leave the pipeline, and returns the first one
+	// processed by a terminal step or reaching the output of the pipeline. The tokens derived from the item are not sent to
+	// the output, so it doesn't have to be consumed. Other tokens keep streaming through the pipeline meanwhile. It returns the context error
+	// if the context is done first, and ErrNoResult if all the derived tokens are filtered or dropped.
+	// It returns ErrEnvelopeRequired if the pipeline is not in envelope mode. A token retained by a buffer step is waited
+	// for till the buffer is flushed or the token is evicted from it, so a context with a deadline has to be used with buffers.
+	Process(ctx context.Context, item I) (I, error)
+
+	// FeedWithPriority sets the priority of the envelope and feeds it to the pipeline.
+	// The priority is used by the steps having an input queue. It returns ErrEnvelopeRequired if the pipeline is not in envelope mode.
+	FeedWithPriority(i I, priority Priority) error
 
 	// TokensCount returns the number of tokens being processed by the pipeline.
 	TokensCount() uint64
+
+	// Output returns the channel from which the tokens sent by the last step are received if it is a step passing its tokens on,
+	// like a basic, buffer, filter, fragmenter, or rate limiter step.
+	// The tokens are counted as done once they are received. It is closed once the pipeline is terminated,
+	// and replaced once it is reset. It is nil if the last step is terminal or custom, or the pipeline is not initialized.
+	Output() <-chan I
+
+	// OutputSeq returns an iterator over the tokens received from Output till it is closed.
+	OutputSeq() iter.Seq[I]
+
+	// RunAll runs the pipeline over the inputs, and returns the outputs of the pipeline with the errors reported meanwhile.
+	// The pipeline is initialized if it is not, and it is always terminated before returning. Once all the inputs are fed,
+	// the buffer steps are flushed from the first to the last by running their time triggered process once more over the
+	// retained tokens. The retained tokens are dropped if the buffer has no time triggered process or it sends nothing.
+	// It requires the tokens count to be tracked, and returns ErrTokensCountNotTracked otherwise.
+	RunAll(ctx context.Context, inputs []I) ([]I, error)
+
+	// RunSeq is the same as RunAll, but feeds the inputs from an iterator.
+	RunSeq(ctx context.Context, inputs iter.Seq[I]) ([]I, error)
+
+	// Describe returns the topology of the pipeline with the current queue depths of the steps.
+	Describe() PipelineDescription
+
+	// State returns the current lifecycle state of the pipeline.
+	State() PipelineState
+
+	// RecentErrors returns the most recent errors reported by the pipeline and its steps.
+	RecentErrors() []PipelineError
+
+	// ScaleStep changes the number of running replicas of the step with the given label.
+	// Retired replicas finish the tokens they are processing before they stop.
+	ScaleStep(label string, replicas uint16) error
+
+	// Pause stops the replicas of all steps from receiving tokens from their input channels while keeping
+	// the tokens in the channels and the buffers intact. It blocks till the tokens being processed are passed to the following steps.
+	Pause() error
+
+	// Resume restarts the replicas stopped by Pause.
+	Resume() error
+
+	// Reset recreates the channels of an initialized or terminated pipeline so that it can be run again.
+	// The tokens retained by buffer steps are kept if preserveBuffers is true and dropped otherwise.
+	Reset(preserveBuffers bool) error
+
+	// Restart resets a terminated pipeline and runs it again with the given context.
+	// The steps are run with the number of replicas they were scaled to when the pipeline was terminated.
+	Restart(ctx context.Context, preserveBuffers bool) error
+
+	// Checkpoint saves the state of the steps having a checkpoint configuration.
+	// The checkpoints are restored by Init, and saved periodically while running and once the pipeline is terminated.
+	Checkpoint() error
 }
 
 // pipeline is a struct that represents a pipeline.
@@ -50,9 +146,24 @@ type pipeline[I any] struct {
 	// defaultChannelSize is the default buffer size used for all channels which has no input channel size set explicitly.
 	defaultChannelSize uint16
 
+	// queues is the input queue of each step. It is nil for the steps receiving from a plain channel.
+	queues []*inputQueue[I]
+
+	// stepsCtx is the context from which the context of every replica is derived.
+	stepsCtx context.Context
+
 	// cancelStepsContext is used to cancel the context of the steps.
 	cancelStepsContext context.CancelFunc
 
+	// replicas keeps track of the running replicas of each step.
+	replicas []*stepReplicas
+
+	// replicasMutex protects replicas from race conditions.
+	replicasMutex sync.Mutex
+
+	// pausing is closed once the replicas stopped by Pause are done. It is nil if the pipeline is not being paused.
+	pausing chan struct{}
+
 	// stepsWaitGroup is used to wait for all the step routines to receive ctx cancel signal.
 	stepsWaitGroup *sync.WaitGroup
 
@@ -68,14 +179,54 @@ type pipeline[I any] struct {
 	// doneCond is used to wait till all tokens are processed.
 	doneCond *sync.Cond
 
-	// initOnce is used to initialize the pipeline only once.
-	initOnce sync.Once
+	// state is the current lifecycle state of the pipeline.
+	state PipelineState
+
+	// stateMutex protects state from race conditions.
+	stateMutex sync.Mutex
+
+	// errorHandler is called with every reported error.
+	errorHandler func(PipelineError)
+
+	// recentErrors is the list of the most recent reported errors.
+	recentErrors []PipelineError
+
+	// collectedErrors is all the errors reported while the pipeline is run by RunSeq. It is nil otherwise.
+	collectedErrors *[]error
 
-	// runOnce is used to run the pipeline only once.
-	runOnce sync.Once
+	// errorsMutex protects recentErrors and collectedErrors from race conditions.
+	errorsMutex sync.Mutex
 
-	// channelsClosed is used to signal that all channels are closed.
-	channelsClosed bool
+	// watchdog is the configuration of the watchdog. The watchdog is disabled if it is nil.
+	watchdog *WatchdogConfig
+
+	// autoscaleInterval is the interval at which the autoscaling policies of the steps are evaluated.
+	autoscaleInterval time.Duration
+
+	// lineages tracks the tokens derived from the fed tokens which are logged or followed by handles.
+	lineages *lineageRegistry
+
+	// wal is the write-ahead log persisting the fed tokens. It is nil if the tokens are not logged.
+	wal *writeAheadLog[I]
+
+	// feedMutex is held for reading while an item is being fed, and for writing while the channels are being created or
+	// closed, so that the items are never sent to a closed channel.
+	feedMutex sync.RWMutex
+
+	// stopping is closed once the pipeline starts terminating, so that the blocked feeds give up before the channels are closed.
+	stopping chan struct{}
+
+	// exit is the output channel of the last step, and output is the channel from which the consumer receives its tokens.
+	// Both are nil if the last step doesn't pass its tokens on.
+	exit   chan I
+	output chan I
+
+	// scaledReplicas is the number of replicas each step was scaled to when the pipeline was terminated, so that it is
+	// restarted with the same replicas. It is nil before the pipeline is terminated for the first time.
+	scaledReplicas []uint16
+
+	// forwarding is the number of tokens received from the exit and waiting to be received from the output.
+	forwarding atomic.Int64
 }
 
 func (p *pipeline[I]) Init() error {
@@ -94,88 +245,207 @@ func (p *pipeline[I]) Init() error {
 		}
 	}
 
-	p.initOnce.Do(func() {
-		// creating a condition variable for the done condition
-		p.doneCond = sync.NewCond(&p.tokensCountMutex)
-
-		// getting the number of steps and channels
-		stepsCount := len(p.steps)
-
-		// creating the required channels
-		// an input for each step
-		allChannels := make([]chan I, stepsCount)
-		for i := 0; i < stepsCount; i++ {
-			// check if the channel size is set for this step.
-			if p.steps[i].GetInputChannelSize() > 0 {
-				allChannels[i] = make(chan I, p.steps[i].GetInputChannelSize())
-			} else {
-				// if not we will use the default channel size.
-				allChannels[i] = make(chan I, p.defaultChannelSize)
-				p.steps[i].SetInputChannelSize(p.defaultChannelSize)
-			}
-		}
+	p.replicasMutex.Lock()
+	defer p.replicasMutex.Unlock()
+
+	if state := p.State(); state != StateCreated {
+		return &StateError{Op: "init", State: state}
+	}
+
+	// creating a condition variable for the done condition
+	p.doneCond = sync.NewCond(&p.tokensCountMutex)
 
-		// setting channels for each step
-		for i := 0; i < stepsCount-1; i++ {
-			p.steps[i].SetInputChannel(allChannels[i])
-			p.steps[i].SetOutputChannel(allChannels[i+1])
-			// setting decrement in case of filtering occurs at the step
-			p.steps[i].SetDecrementTokensCountHandler(p.decrementTokensCount)
-			// setting increment in case of fragmentation occurs at the step
-			p.steps[i].SetIncrementTokensCountHandler(p.incrementTokensCount)
+	if err := p.restoreCheckpoints(); err != nil {
+		return err
+	}
+
+	if p.wal != nil {
+		if err := p.wal.open(); err != nil {
+			return fmt.Errorf("opening write-ahead log: %w", err)
 		}
+	}
+
+	p.connectSteps()
 
-		// setting the input for the terminal step.
-		stepBeforeTerminalStepIndex := stepsCount - 2
-		terminalStepIndex := stepsCount - 1
-		// setting the input for the terminal step from the step before it.
-		p.steps[terminalStepIndex].SetInputChannel(p.steps[stepBeforeTerminalStepIndex].GetOutputChannel())
-		// setting the decrement for the terminal step.
-		p.steps[terminalStepIndex].SetDecrementTokensCountHandler(p.decrementTokensCount)
-	})
+	p.setState(StateInitialized)
 	return nil
 }
 
-func (p *pipeline[I]) Run(ctx context.Context) {
-	p.runOnce.Do(func() {
-		// creating a wait group for all the step routines.
-		p.stepsWaitGroup = &sync.WaitGroup{}
+// connectSteps creates the channels connecting the steps and sets the tokens count handlers.
+func (p *pipeline[I]) connectSteps() {
+	p.feedMutex.Lock()
+	defer p.feedMutex.Unlock()
+	p.stopping = make(chan struct{})
+
+	// getting the number of steps and channels
+	stepsCount := len(p.steps)
+
+	// creating the required channels
+	// an input for each step, and an inlet to which the previous step sends.
+	// both are the same channel unless the step has an input queue.
+	inputs := make([]chan I, stepsCount)
+	inlets := make([]chan I, stepsCount)
+	p.queues = make([]*inputQueue[I], stepsCount)
+	for i := 0; i < stepsCount; i++ {
+		// check if the channel size is not set for this step to use the default channel size.
+		if p.steps[i].GetInputChannelSize() == 0 {
+			p.steps[i].SetInputChannelSize(p.defaultChannelSize)
+		}
+		size := p.steps[i].GetInputChannelSize()
+		if q, ok := p.steps[i].(queued[I]); ok && q.GetInputQueue() != nil {
+			label := p.steps[i].GetLabel()
+			p.queues[i] = newInputQueue(*q.GetInputQueue(), int(size), p.decrementTokensCount, func(err error) { p.reportError(label, err) })
+			p.queues[i].lineages = p.lineages
+			inputs[i] = p.queues[i].output
+			inlets[i] = p.queues[i].inlet
+		} else {
+			inputs[i] = make(chan I, size)
+			inlets[i] = inputs[i]
+		}
+	}
+
+	// the lineages are tracked by the steps which derive or release tokens, and the errors of the steps are reported.
+	for _, step := range p.steps {
+		if tracked, ok := step.(lineageTracked); ok {
+			tracked.setLineageRegistry(p.lineages)
+		}
+		if reporting, ok := step.(errorReporting); ok {
+			label := step.GetLabel()
+			reporting.setErrorReporter(func(err error) { p.reportError(label, err) })
+		}
+	}
+
+	// setting channels for each step
+	for i := 0; i < stepsCount-1; i++ {
+		p.steps[i].SetInputChannel(inputs[i])
+		p.steps[i].SetOutputChannel(inlets[i+1])
+		// setting decrement in case of filtering occurs at the step
+		p.steps[i].SetDecrementTokensCountHandler(p.decrementTokensCount)
+		// setting increment in case of fragmentation occurs at the step
+		p.steps[i].SetIncrementTokensCountHandler(p.incrementTokensCount)
+	}
+
+	// setting the input for the terminal step.
+	terminalStepIndex := stepsCount - 1
+	p.steps[terminalStepIndex].SetInputChannel(inputs[terminalStepIndex])
+	// setting the decrement for the terminal step.
+	p.steps[terminalStepIndex].SetDecrementTokensCountHandler(p.decrementTokensCount)
+
+	// a last step which passes its tokens on sends them to the output of the pipeline.
+	p.exit, p.output = nil, nil
+	if _, ok := p.steps[terminalStepIndex].(outputStep); ok {
+		p.exit = make(chan I, p.steps[terminalStepIndex].GetInputChannelSize())
+		p.output = make(chan I)
+		p.steps[terminalStepIndex].SetOutputChannel(p.exit)
+		p.steps[terminalStepIndex].SetIncrementTokensCountHandler(p.incrementTokensCount)
+	}
+}
+
+func (p *pipeline[I]) Run(ctx context.Context) error {
+	p.replicasMutex.Lock()
+	defer p.replicasMutex.Unlock()
+
+	if state := p.State(); state != StateInitialized {
+		return &StateError{Op: "run", State: state}
+	}
+
+	// creating a wait group for all the step routines.
+	p.stepsWaitGroup = &sync.WaitGroup{}
+
+	// creating a child context for the steps from the parent context.
+	stepsCtx, cancel := context.WithCancel(ctx)
+	p.stepsCtx = stepsCtx
+	p.cancelStepsContext = cancel
+
+	// the activity tracking has to be enabled before spawning the replicas.
+	var activities []*stepActivity
+	if p.watchdog != nil {
+		activities = p.trackActivities()
+	}
+
+	// running steps in reverse order
+	p.replicas = make([]*stepReplicas, len(p.steps))
+	for i := len(p.steps) - 1; i >= 0; i-- {
+		p.replicas[i] = &stepReplicas{}
+		// spawning the replicas for each step
+		for range p.configuredReplicas(i) {
+			p.spawnReplica(i)
+		}
+	}
 
-		// creating a child context for the steps from the parent context.
-		stepsCtx, cancel := context.WithCancel(ctx)
-		p.cancelStepsContext = cancel
+	for _, q := range p.queues {
+		if q != nil {
+			p.stepsWaitGroup.Add(1)
+			go q.run(stepsCtx, p.stepsWaitGroup)
+		}
+	}
+
+	if p.exit != nil {
+		p.stepsWaitGroup.Add(1)
+		go p.forwardOutput(stepsCtx, p.stepsWaitGroup, p.exit, p.output)
+	}
+
+	for _, step := range p.steps {
+		if c, ok := step.(checkpointed); ok && c.checkpointInterval() > 0 {
+			p.stepsWaitGroup.Add(1)
+			go p.runCheckpoints(stepsCtx, p.stepsWaitGroup, step, c.checkpointInterval())
+		}
+	}
 
-		// running steps in reverse order
-		for i := len(p.steps) - 1; i >= 0; i-- {
-			// spawning the replicas for each step
-			for range p.steps[i].GetReplicas() {
-				p.stepsWaitGroup.Add(1)
-				go p.steps[i].Run(stepsCtx, p.stepsWaitGroup)
-			}
+	if p.wal != nil {
+		// the replayed tokens are counted before running so that WaitTillDone waits for them.
+		replay := p.wal.takeReplay()
+		for range replay {
+			p.incrementTokensCount()
 		}
-	})
+		p.stepsWaitGroup.Add(1)
+		go p.replayWAL(stepsCtx, p.stepsWaitGroup, replay)
+	}
+
+	if p.watchdog != nil {
+		p.stepsWaitGroup.Add(1)
+		go p.runWatchdog(stepsCtx, p.stepsWaitGroup, activities)
+	}
+
+	if states := p.autoscaleStates(); len(states) > 0 {
+		p.stepsWaitGroup.Add(1)
+		go p.runAutoscaler(stepsCtx, p.stepsWaitGroup, states)
+	}
+
+	p.setState(StateRunning)
+	return nil
 }
 
-func (p *pipeline[I]) WaitTillDone() {
+func (p *pipeline[I]) WaitTillDone() error {
+	if state := p.State(); state == StateCreated {
+		return &StateError{Op: "wait for", State: state}
+	}
 	if !p.trackTokensCount {
-		return
+		return ErrTokensCountNotTracked
 	}
 	p.doneCond.L.Lock()
 	defer p.doneCond.L.Unlock()
 	for p.tokensCount > 0 {
 		p.doneCond.Wait()
 	}
+	return nil
 }
 
-func (p *pipeline[I]) Terminate() {
+func (p *pipeline[I]) Terminate() error {
+
+	// changing the state while holding the replicas mutex so that no replicas are spawned after this point.
+	p.replicasMutex.Lock()
 
 	// checking the steps are running
-	if p.stepsWaitGroup == nil && p.channelsClosed {
-		return
+	if state := p.State(); state != StateRunning && state != StatePaused {
+		p.replicasMutex.Unlock()
+		return &StateError{Op: "terminate", State: state}
 	}
 
-	// setting closed channel signal immediately so that the input stops.
-	p.channelsClosed = true
+	// changing the state immediately so that the input stops.
+	p.setState(StateDraining)
+	close(p.stopping)
+	p.replicasMutex.Unlock()
 
 	// canceling the context in case the parent context is not cancelled.
 	p.cancelStepsContext()
@@ -183,31 +453,140 @@ func (p *pipeline[I]) Terminate() {
 	// wait for step routines to be done
 	p.stepsWaitGroup.Wait()
 
-	// closing all channels
-	for _, step := range p.steps {
+	// saving the final state of the steps after they are stopped. The errors are reported.
+	p.saveCheckpoints()
+
+	// the tokens in the channels are discarded, so their lineages can't be done.
+	// The tokens which are not acknowledged stay in the log to be replayed.
+	p.lineages.abandon(ErrTokenAbandoned)
+	if p.wal != nil {
+		p.wal.close()
+	}
+
+	// closing all channels once the feeds in progress gave up.
+	p.feedMutex.Lock()
+	for i, step := range p.steps {
 		close(step.GetInputChannel())
+		if p.queues[i] != nil {
+			close(p.queues[i].inlet)
+		}
+	}
+	if p.exit != nil {
+		close(p.exit)
+		close(p.output)
 	}
+	p.feedMutex.Unlock()
 
-	// clearing the wait group
+	// clearing the wait group and the replicas, keeping the number of replicas the steps are scaled to for the next run.
 	p.stepsWaitGroup = nil
+	p.replicasMutex.Lock()
+	if p.replicas != nil {
+		p.scaledReplicas = make([]uint16, len(p.replicas))
+	}
+	for i, replicas := range p.replicas {
+		p.scaledReplicas[i] = uint16(len(replicas.cancels)) + replicas.paused
+		replicas.cancels = nil
+	}
+	p.replicasMutex.Unlock()
+	p.setState(StateTerminated)
+	return nil
 }
 
-func (p *pipeline[I]) FeedOne(item I) {
-	if p.channelsClosed {
-		return
+func (p *pipeline[I]) FeedOne(item I) error {
+	return p.feed(context.Background(), item, nil)
+}
+
+// feed feeds the item to the pipeline unless the context is cancelled first, and completes the handle once
+// all the tokens derived from it are done if it is set.
+func (p *pipeline[I]) feed(ctx context.Context, item I, handle *TokenHandle) error {
+	p.feedMutex.RLock()
+	defer p.feedMutex.RUnlock()
+
+	if err := p.admit(item, handle); err != nil {
+		return err
+	}
+	select {
+	case p.inlet(0) <- item:
+		return nil
+	case <-p.stopping:
+		// the pipeline is being terminated, so the state error is reported.
+		p.reject(item)
+		return p.checkFeedable()
+	case <-ctx.Done():
+		p.reject(item)
+		return ctx.Err()
+	}
+}
+
+func (p *pipeline[I]) TryFeed(item I) error {
+	p.feedMutex.RLock()
+	defer p.feedMutex.RUnlock()
+
+	if err := p.checkFeedable(); err != nil {
+		return err
+	}
+	// checking the room before admitting the item, so that the rejected items are not persisted.
+	// An unbuffered input has room only if a replica is waiting, so it is checked by the send.
+	if length, capacity := p.queueDepth(0); capacity > 0 && length >= capacity {
+		return ErrPipelineFull
+	}
+	if err := p.admit(item, nil); err != nil {
+		return err
 	}
+	// the room can be taken by concurrent feeds after it is checked.
+	if !p.offer(0, item) {
+		p.reject(item)
+		return ErrPipelineFull
+	}
+	return nil
+}
+
+// admit prepares the item to be sent to the first step. The item is stamped, persisted, tracked and counted.
+func (p *pipeline[I]) admit(item I, handle *TokenHandle) error {
+	if err := p.checkFeedable(); err != nil {
+		return err
+	}
+	restampEnvelope(item)
+	// the token is accepted only once it is persisted.
+	seq, err := p.logToken(item)
+	if err != nil {
+		p.reportError("", err)
+		return err
+	}
+	p.trackLineage(item, seq, handle)
 	p.incrementTokensCount()
-	p.steps[0].GetInputChannel() <- item
+	return nil
 }
 
-func (p *pipeline[I]) FeedMany(items []I) {
+// reject removes the admitted token which is not accepted by the first step from the pipeline.
+func (p *pipeline[I]) reject(item I) {
+	if h := headerOf(item); h != nil {
+		p.lineages.release(h.getRoots(), outcomeDropped)
+	}
+	p.decrementTokensCount()
+}
+
+func (p *pipeline[I]) FeedMany(items []I) error {
 	for _, item := range items {
-		if p.channelsClosed {
-			return
+		if err := p.FeedOne(item); err != nil {
+			return err
 		}
-		p.incrementTokensCount()
-		p.steps[0].GetInputChannel() <- item
 	}
+	return nil
+}
+
+// checkFeedable returns an error if the channels of the pipeline are not created yet or are being closed.
+// The tokens fed while the channels are being closed are also reported as they are discarded.
+func (p *pipeline[I]) checkFeedable() error {
+	switch state := p.State(); state {
+	case StateCreated:
+		return &StateError{Op: "feed", State: state}
+	case StateDraining, StateTerminated:
+		err := &StateError{Op: "feed", State: state}
+		p.reportError("", err)
+		return err
+	}
+	return nil
 }
 
 func (p *pipeline[I]) TokensCount() uint64 {
@@ -2,6 +2,8 @@ package pipelines
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"testing"
 	"time"
 )
@@ -195,6 +197,81 @@ func TestPipeline_FeedMany(t *testing.T) {
 	}
 }
 
+func TestPipeline_TryFeed(t *testing.T) {
+	builder := &Builder[int]{}
+	sink := builder.NewStep(StepTerminalConfig[int]{Process: func(int) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 2, TrackTokensCount: true}, sink)
+
+	if err := p.TryFeed(1); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState before init, got %v", err)
+	}
+
+	// feeding without running so that the tokens stay in the input channel.
+	p.Init()
+	for i := range 2 {
+		if err := p.TryFeed(i); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := p.TryFeed(3); !errors.Is(err, ErrPipelineFull) {
+		t.Errorf("expected ErrPipelineFull, got %v", err)
+	}
+	if p.TokensCount() != 2 {
+		t.Errorf("expected the rejected token not to be counted, got tokens count %d", p.TokensCount())
+	}
+
+	p.Run(context.Background())
+	p.WaitTillDone()
+	if err := p.TryFeed(3); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	p.Terminate()
+}
+
+func TestPipeline_FeedWhileTerminating(t *testing.T) {
+	builder := &Builder[int]{}
+	sink := builder.NewStep(StepTerminalConfig[int]{Process: func(int) { time.Sleep(time.Millisecond) }})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 1, TrackTokensCount: true}, sink)
+	p.Init()
+	p.Run(context.Background())
+
+	// the feeds blocked on the full channel give up once the pipeline is terminated, instead of sending to the closed channel.
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := range 10 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for {
+				if err := p.FeedOne(i); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for {
+				if err := p.TryFeed(i); err != nil && !errors.Is(err, ErrPipelineFull) {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := p.Terminate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if !errors.Is(err, ErrInvalidState) {
+			t.Errorf("expected ErrInvalidState, got %v", err)
+		}
+	}
+}
+
 func TestPipeline_Terminate(t *testing.T) {
 	steps := []IStep[int]{
 		&mockStep[int]{replicas: 1},
@@ -0,0 +1,28 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoResult is returned by Process when none of the tokens derived from the item is processed by a terminal step.
+var ErrNoResult = errors.New("no token reached the end of the pipeline")
+
+func (p *pipeline[I]) Process(ctx context.Context, item I) (I, error) {
+	var result I
+	if headerOf(item) == nil {
+		return result, ErrEnvelopeRequired
+	}
+	handle := newTokenHandle()
+	handle.awaitsResult = true
+	if err := p.feed(ctx, item, handle); err != nil {
+		return result, err
+	}
+	if _, err := handle.Wait(ctx); err != nil {
+		return result, err
+	}
+	if handle.result == nil {
+		return result, ErrNoResult
+	}
+	return handle.result.(I), nil
+}
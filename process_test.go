@@ -0,0 +1,67 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPipeline_Process(t *testing.T) {
+	release := make(chan struct{})
+	var streamed atomic.Int64
+	builder := &Builder[*Envelope[int]]{}
+	double := builder.NewStep(StepBasicConfig[*Envelope[int]]{
+		Label:   "double",
+		Process: func(e *Envelope[int]) *Envelope[int] { return NewEnvelope(e.Value * 2) },
+	})
+	positive := builder.NewStep(StepFilterConfig[*Envelope[int]]{
+		Label:        "positive",
+		PassCriteria: func(e *Envelope[int]) bool { return e.Value > 0 },
+	})
+	sink := builder.NewStep(StepTerminalConfig[*Envelope[int]]{
+		Label: "sink",
+		Process: func(e *Envelope[int]) {
+			if e.Value == 200 {
+				<-release
+			}
+			streamed.Add(1)
+		},
+	})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, double, positive, sink)
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+
+	// the streamed tokens are processed concurrently with the request.
+	p.FeedMany([]*Envelope[int]{NewEnvelope(1), NewEnvelope(2), NewEnvelope(3)})
+	result, err := p.Process(context.Background(), NewEnvelope(21))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Value != 42 {
+		t.Errorf("expected 42, got %d", result.Value)
+	}
+
+	if _, err := p.Process(context.Background(), NewEnvelope(-1)); !errors.Is(err, ErrNoResult) {
+		t.Errorf("expected ErrNoResult, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.Process(ctx, NewEnvelope(100)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	close(release)
+}
+
+func TestPipeline_Process_EnvelopeRequired(t *testing.T) {
+	builder := &Builder[int]{}
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(int) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, sink)
+	p.Init()
+	if _, err := p.Process(context.Background(), 1); !errors.Is(err, ErrEnvelopeRequired) {
+		t.Errorf("expected ErrEnvelopeRequired, got %v", err)
+	}
+}
@@ -0,0 +1,448 @@
+package pipelines
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrEnvelopeRequired is returned when a feature requires the pipeline to run in envelope mode.
+var ErrEnvelopeRequired = errors.New("pipeline is not running in envelope mode")
+
+// Priority is the priority of a token in the input queues of the steps. Tokens with higher priority are processed first.
+type Priority int
+
+const (
+	// PriorityLow is the priority of tokens which can wait for the others, like backfills.
+	PriorityLow Priority = iota - 1
+
+	// PriorityNormal is the default priority of the tokens.
+	PriorityNormal
+
+	// PriorityHigh is the priority of tokens which have to overtake the others, like real-time alarms.
+	PriorityHigh
+)
+
+// OverflowPolicy is the action taken by an input queue when a token is sent to it while it is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the sender till the queue has room for the token.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropNewest drops the token sent to the full queue.
+	OverflowDropNewest
+
+	// OverflowDropOldest drops the token which has been waiting the longest in the queue to make room for the sent token.
+	OverflowDropOldest
+
+	// OverflowDivert passes the token sent to the full queue to the divert function of the queue.
+	OverflowDivert
+)
+
+// InputQueueConfig is the configuration of the queue holding the tokens waiting for a step.
+// The queue replaces the input channel of the step and has the same capacity.
+type InputQueueConfig[I any] struct {
+
+	// Priority returns the priority of the token. If it is not set, the priority of the envelope is used in envelope mode
+	// and all tokens have the normal priority otherwise.
+	Priority func(I) Priority
+
+	// Aging raises the priority of a waiting token by one level every time the aging period passes, so that
+	// tokens with low priority are not starved by a continuous flow of tokens with high priority. It is disabled if it is not set.
+	Aging time.Duration
+
+	// Overflow is the action taken when a token is sent to the full queue. It is OverflowBlock if it is not set.
+	// Dropped and diverted tokens leave the pipeline.
+	Overflow OverflowPolicy
+
+	// Divert is called with the overflowing tokens if Overflow is OverflowDivert. It blocks the queue while it is running.
+	Divert func(I)
+
+	// Spill enables spilling the tokens to disk when the queue is full. The overflow policy is applied only when
+	// the disk budget is exhausted. It is optional.
+	Spill *SpillConfig[I]
+}
+
+// queued is implemented by the steps which have an input queue.
+type queued[I any] interface {
+	GetInputQueue() *InputQueueConfig[I]
+}
+
+func (s *stepBase[I]) GetInputQueue() *InputQueueConfig[I] {
+	return s.inputQueue
+}
+
+// setInputQueue validates and sets the input queue configuration of the step.
+func (s *stepBase[I]) setInputQueue(config *InputQueueConfig[I]) {
+	if config == nil {
+		return
+	}
+	if config.Aging < 0 {
+		panic("input queue aging must not be negative")
+	}
+	if config.Overflow < OverflowBlock || config.Overflow > OverflowDivert {
+		panic("unknown input queue overflow policy")
+	}
+	if config.Overflow == OverflowDivert && config.Divert == nil {
+		panic("divert is required when overflowing tokens are diverted")
+	}
+	if config.Spill != nil {
+		validateSpillConfig(config.Spill)
+	}
+	validated := *config
+	s.inputQueue = &validated
+}
+
+// queuedToken is a token waiting in the input queue.
+type queuedToken[I any] struct {
+	token I
+
+	// rank is the priority of the token reduced by the time it was enqueued in aging periods.
+	// Since all waiting tokens age at the same rate, the order of the ranks doesn't change while waiting.
+	rank float64
+
+	// seq is the order of the token in the queue used to keep the tokens of the same rank in FIFO order.
+	seq uint64
+}
+
+// tokenHeap is a max heap of the queued tokens ordered by rank.
+type tokenHeap[I any] []queuedToken[I]
+
+func (h tokenHeap[I]) Len() int { return len(h) }
+
+func (h tokenHeap[I]) Less(i, j int) bool {
+	if h[i].rank != h[j].rank {
+		return h[i].rank > h[j].rank
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h tokenHeap[I]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *tokenHeap[I]) Push(x any) { *h = append(*h, x.(queuedToken[I])) }
+
+func (h *tokenHeap[I]) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	old[len(old)-1] = queuedToken[I]{}
+	*h = old[:len(old)-1]
+	return last
+}
+
+// inputQueue reorders the tokens sent to a step by their priority and applies the overflow policy when it is full.
+// The previous step sends to the inlet, and the replicas of the step receive from the output.
+// If spilling is enabled, the tokens sent while the queue is full are written to disk, and the following tokens are
+// written after them till they are all read back into the queue, so the spilled tokens keep their order.
+type inputQueue[I any] struct {
+	config   InputQueueConfig[I]
+	capacity int
+
+	// inlet is the channel receiving the tokens from the previous step or the pipeline input.
+	inlet chan I
+
+	// output is the channel the replicas of the step receive the tokens from.
+	output chan I
+
+	// wake is signaled when a token is offered to the queue without passing by the inlet.
+	wake chan struct{}
+
+	// created is the reference time of the aging.
+	created time.Time
+
+	// tokens is the heap of the waiting tokens.
+	tokens tokenHeap[I]
+
+	// nextSeq is the sequence of the next queued token.
+	nextSeq uint64
+
+	// mutex protects tokens from race conditions.
+	mutex sync.Mutex
+
+	// decrementTokensCount is called for every token leaving the pipeline due to overflow.
+	decrementTokensCount func()
+
+	// dropped is the number of tokens dropped due to overflow.
+	dropped atomic.Uint64
+
+	// diverted is the number of tokens diverted due to overflow.
+	diverted atomic.Uint64
+
+	// spill is the file holding the spilled tokens. It is nil if spilling is disabled.
+	spill *spillFile[I]
+
+	// reportError is called with the errors of spilling the tokens.
+	reportError func(error)
+
+	// lineages tracks the tokens derived from the fed tokens. It is nil if the pipeline doesn't track lineages.
+	lineages *lineageRegistry
+}
+
+func newInputQueue[I any](config InputQueueConfig[I], capacity int, decrementTokensCount func(), reportError func(error)) *inputQueue[I] {
+	q := &inputQueue[I]{
+		config:               config,
+		capacity:             capacity,
+		inlet:                make(chan I),
+		output:               make(chan I),
+		wake:                 make(chan struct{}, 1),
+		created:              time.Now(),
+		decrementTokensCount: decrementTokensCount,
+		reportError:          reportError,
+	}
+	if config.Spill != nil {
+		q.spill = newSpillFile(*config.Spill)
+	}
+	return q
+}
+
+// priorityOf returns the priority of the token.
+func (q *inputQueue[I]) priorityOf(token I) Priority {
+	if q.config.Priority != nil {
+		return q.config.Priority(token)
+	}
+	if h := headerOf(token); h != nil {
+		return h.Priority()
+	}
+	return PriorityNormal
+}
+
+// rankOf returns the rank of the token enqueued now.
+func (q *inputQueue[I]) rankOf(token I) float64 {
+	rank := float64(q.priorityOf(token))
+	if q.config.Aging > 0 {
+		rank -= float64(time.Since(q.created)) / float64(q.config.Aging)
+	}
+	return rank
+}
+
+func (q *inputQueue[I]) push(token I) {
+	rank := q.rankOf(token)
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	heap.Push(&q.tokens, queuedToken[I]{token: token, rank: rank, seq: q.nextSeq})
+	q.nextSeq++
+}
+
+// offer pushes the token to the queue without blocking if it has room for it, and returns false otherwise.
+// The overflow policy is not applied, and the token is not spilled.
+func (q *inputQueue[I]) offer(token I) bool {
+	// the spilled tokens are read back before the offered ones to keep their order.
+	if q.spill != nil && !q.spill.empty() {
+		return false
+	}
+	rank := q.rankOf(token)
+	q.mutex.Lock()
+	if len(q.tokens) >= q.capacity {
+		q.mutex.Unlock()
+		return false
+	}
+	heap.Push(&q.tokens, queuedToken[I]{token: token, rank: rank, seq: q.nextSeq})
+	q.nextSeq++
+	q.mutex.Unlock()
+
+	// waking the run loop in case it is waiting for the queue to have tokens.
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// peek returns the token with the highest rank if the queue is not empty.
+func (q *inputQueue[I]) peek() (I, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.tokens) == 0 {
+		var zero I
+		return zero, false
+	}
+	return q.tokens[0].token, true
+}
+
+func (q *inputQueue[I]) pop() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	heap.Pop(&q.tokens)
+}
+
+// removeOldest removes the token which has been waiting the longest in the queue.
+func (q *inputQueue[I]) removeOldest() I {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	oldest := 0
+	for i := range q.tokens {
+		if q.tokens[i].seq < q.tokens[oldest].seq {
+			oldest = i
+		}
+	}
+	return heap.Remove(&q.tokens, oldest).(queuedToken[I]).token
+}
+
+// enqueue pushes the token to the queue, spills it if the queue is full, and applies the overflow policy if it can't be spilled.
+func (q *inputQueue[I]) enqueue(token I) {
+	spilling := q.spill != nil && !q.spill.empty()
+	if !spilling && q.len() < q.capacity {
+		q.push(token)
+		return
+	}
+	if q.spill != nil {
+		err := q.spill.write(token)
+		if err == nil {
+			return
+		}
+		if !errors.Is(err, errSpillFull) {
+			q.reportError(err)
+		}
+	}
+	q.overflow(token)
+}
+
+// overflow applies the overflow policy to the token which can't be queued.
+func (q *inputQueue[I]) overflow(token I) {
+	switch q.config.Overflow {
+	case OverflowDropNewest:
+		q.dropped.Add(1)
+		q.discard(token)
+	case OverflowDropOldest:
+		q.discard(q.removeOldest())
+		q.dropped.Add(1)
+		q.push(token)
+	case OverflowDivert:
+		q.config.Divert(token)
+		q.diverted.Add(1)
+		q.discard(token)
+	default:
+		// the token is received only when it is expected to fit. Otherwise, it is queued beyond the capacity instead of being lost.
+		q.push(token)
+	}
+}
+
+// discard counts the dropped or diverted token leaving the pipeline.
+func (q *inputQueue[I]) discard(token I) {
+	if h := headerOf(token); h != nil {
+		q.lineages.release(h.getRoots(), outcomeDropped)
+	}
+	q.decrementTokensCount()
+}
+
+// unspill reads the spilled tokens back into the queue while it has room.
+func (q *inputQueue[I]) unspill() {
+	for q.spill != nil && !q.spill.empty() && q.len() < q.capacity {
+		token, lost, err := q.spill.read()
+		if err != nil {
+			q.reportError(err)
+			q.dropSpilled(lost)
+			continue
+		}
+		q.push(token)
+	}
+}
+
+// dropSpilled counts the spilled tokens which are lost as dropped tokens leaving the pipeline.
+// Their lineages can't be released since they are not decoded, so they are abandoned once the pipeline is terminated.
+func (q *inputQueue[I]) dropSpilled(lost int) {
+	q.dropped.Add(uint64(lost))
+	for range lost {
+		q.decrementTokensCount()
+	}
+}
+
+// accepting returns whether the inlet should be received from. With the block policy, it is not received from while the
+// queue is full and the tokens can't be spilled, so that the previous step is blocked as with a full channel.
+func (q *inputQueue[I]) accepting() bool {
+	if q.config.Overflow != OverflowBlock {
+		return true
+	}
+	if q.spill != nil {
+		return !q.spill.full
+	}
+	return q.len() < q.capacity
+}
+
+// spilled returns the number of tokens spilled to disk.
+func (q *inputQueue[I]) spilled() int {
+	if q.spill == nil {
+		return 0
+	}
+	return int(q.spill.count.Load())
+}
+
+func (q *inputQueue[I]) len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.tokens)
+}
+
+// run moves the tokens from the inlet to the queue and from the queue to the output till the context is cancelled.
+// The spilled tokens are dropped once it returns.
+func (q *inputQueue[I]) run(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	if q.spill != nil {
+		defer func() {
+			q.dropSpilled(q.spill.discard())
+		}()
+	}
+	for {
+		q.unspill()
+		inlet := q.inlet
+		if !q.accepting() {
+			inlet = nil
+		}
+		output := q.output
+		next, ok := q.peek()
+		if !ok {
+			output = nil
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case token := <-inlet:
+			q.enqueue(token)
+		case <-q.wake:
+		case output <- next:
+			q.pop()
+		}
+	}
+}
+
+// inlet returns the channel receiving the tokens sent to the step at the given index.
+func (p *pipeline[I]) inlet(index int) chan I {
+	if q := p.queues[index]; q != nil {
+		return q.inlet
+	}
+	return p.steps[index].GetInputChannel()
+}
+
+// offer sends the item to the step at the given index without blocking, and returns false if it has no room for it.
+func (p *pipeline[I]) offer(index int, item I) bool {
+	if q := p.queues[index]; q != nil {
+		return q.offer(item)
+	}
+	select {
+	case p.steps[index].GetInputChannel() <- item:
+		return true
+	default:
+		return false
+	}
+}
+
+// queueDepth returns the number of tokens waiting for the step at the given index and the max number of tokens which can wait.
+func (p *pipeline[I]) queueDepth(index int) (int, int) {
+	if p.queues != nil && p.queues[index] != nil {
+		return p.queues[index].len(), p.queues[index].capacity
+	}
+	input := p.steps[index].GetInputChannel()
+	return len(input), cap(input)
+}
+
+func (p *pipeline[I]) FeedWithPriority(item I, priority Priority) error {
+	h := headerOf(item)
+	if h == nil {
+		return ErrEnvelopeRequired
+	}
+	h.SetPriority(priority)
+	return p.FeedOne(item)
+}
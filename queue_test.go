@@ -0,0 +1,246 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func waitQueueLength[I any](t *testing.T, p IPipeline[I], length int) {
+	deadline := time.Now().Add(time.Second)
+	for p.Describe().Steps[0].QueueLength != length {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected queue length %d, got %d", length, p.Describe().Steps[0].QueueLength)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPipeline_FeedWithPriority(t *testing.T) {
+	var order []*Envelope[int]
+	blocked, release := make(chan struct{}), make(chan struct{})
+	builder := &Builder[*Envelope[int]]{}
+	// the step blocks on the token 0 till it is released, so that the following tokens wait in its input queue.
+	work := builder.NewStep(StepBasicConfig[*Envelope[int]]{
+		Label: "work",
+		Process: func(e *Envelope[int]) *Envelope[int] {
+			if e.Value == 0 {
+				close(blocked)
+				<-release
+			}
+			order = append(order, e)
+			return e
+		},
+		StepOptions: StepOptions[*Envelope[int]]{InputQueue: &InputQueueConfig[*Envelope[int]]{}},
+	})
+	sink := builder.NewStep(StepTerminalConfig[*Envelope[int]]{Label: "sink", Process: func(*Envelope[int]) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, work, sink)
+	p.Init()
+	p.Run(context.Background())
+
+	p.FeedOne(NewEnvelope(0))
+	<-blocked
+	p.FeedWithPriority(NewEnvelope(1), PriorityLow)
+	p.FeedOne(NewEnvelope(2))
+	p.FeedWithPriority(NewEnvelope(3), PriorityHigh)
+	p.FeedWithPriority(NewEnvelope(4), PriorityLow)
+	waitQueueLength(t, p, 4)
+
+	close(release)
+	p.WaitTillDone()
+	p.Terminate()
+
+	values := make([]int, len(order))
+	for i, e := range order {
+		values[i] = e.Value
+	}
+	if !reflect.DeepEqual(values, []int{0, 3, 2, 1, 4}) {
+		t.Errorf("expected tokens to be processed by priority, got %v", values)
+	}
+}
+
+func TestPipeline_InputQueue_PriorityFunc(t *testing.T) {
+	var order []int
+	blocked, release := make(chan struct{}), make(chan struct{})
+	builder := &Builder[int]{}
+	work := builder.NewStep(StepBasicConfig[int]{
+		Label: "work",
+		Process: func(i int) int {
+			if i == 0 {
+				close(blocked)
+				<-release
+			}
+			order = append(order, i)
+			return i
+		},
+		StepOptions: StepOptions[int]{
+			InputQueue: &InputQueueConfig[int]{
+				Priority: func(i int) Priority {
+					if i < 0 {
+						return PriorityHigh
+					}
+					return PriorityNormal
+				},
+			},
+		},
+	})
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(int) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, work, sink)
+	p.Init()
+	p.Run(context.Background())
+
+	p.FeedOne(0)
+	<-blocked
+	p.FeedMany([]int{1, 2, -1, 3, -2})
+	waitQueueLength(t, p, 5)
+
+	close(release)
+	p.WaitTillDone()
+	p.Terminate()
+
+	if !reflect.DeepEqual(order, []int{0, -1, -2, 1, 2, 3}) {
+		t.Errorf("expected tokens to be processed by priority, got %v", order)
+	}
+}
+
+func TestPipeline_FeedWithPriority_PlainTokens(t *testing.T) {
+	builder := &Builder[int]{}
+	work := builder.NewStep(StepBasicConfig[int]{
+		Label:       "work",
+		Process:     func(i int) int { return i },
+		StepOptions: StepOptions[int]{InputQueue: &InputQueueConfig[int]{}},
+	})
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(int) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, work, sink)
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+
+	if err := p.FeedWithPriority(1, PriorityHigh); !errors.Is(err, ErrEnvelopeRequired) {
+		t.Errorf("expected ErrEnvelopeRequired, got %v", err)
+	}
+	if p.TokensCount() != 0 {
+		t.Errorf("expected token not to be fed, got tokens count %d", p.TokensCount())
+	}
+}
+
+func TestInputQueue_Aging(t *testing.T) {
+	q := newInputQueue(InputQueueConfig[*Envelope[int]]{Aging: 10 * time.Millisecond}, 10, nil, nil)
+
+	low := NewEnvelope(1)
+	low.SetPriority(PriorityLow)
+	q.push(low)
+
+	// moving the reference time back as if the low priority token has been waiting for 3 aging periods.
+	q.created = q.created.Add(-30 * time.Millisecond)
+	high := NewEnvelope(2)
+	high.SetPriority(PriorityHigh)
+	q.push(high)
+
+	if next, _ := q.peek(); next != low {
+		t.Errorf("expected the aged low priority token to be first, got %d", next.Value)
+	}
+
+	q.pop()
+	if next, _ := q.peek(); next != high {
+		t.Errorf("expected the high priority token to be next, got %d", next.Value)
+	}
+	q.pop()
+	if _, ok := q.peek(); ok || q.len() != 0 {
+		t.Error("expected the queue to be empty")
+	}
+}
+
+func TestInputQueue_NegativeAging(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for negative aging")
+		}
+	}()
+	builder := &Builder[int]{}
+	builder.NewStep(StepBasicConfig[int]{
+		Process:     func(i int) int { return i },
+		StepOptions: StepOptions[int]{InputQueue: &InputQueueConfig[int]{Aging: -time.Second}},
+	})
+}
+
+func TestPipeline_InputQueue_Overflow(t *testing.T) {
+	var diverted []int
+	tests := []struct {
+		name     string
+		queue    *InputQueueConfig[int]
+		expected []int
+		dropped  uint64
+	}{
+		{"DropNewest", &InputQueueConfig[int]{Overflow: OverflowDropNewest}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 2},
+		{"DropOldest", &InputQueueConfig[int]{Overflow: OverflowDropOldest}, []int{0, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}, 2},
+		{"Divert", &InputQueueConfig[int]{Overflow: OverflowDivert, Divert: func(i int) { diverted = append(diverted, i) }}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var order []int
+			blocked, release := make(chan struct{}), make(chan struct{})
+			builder := &Builder[int]{}
+			work := builder.NewStep(StepBasicConfig[int]{
+				Label: "work",
+				Process: func(i int) int {
+					if i == 0 {
+						close(blocked)
+						<-release
+					}
+					order = append(order, i)
+					return i
+				},
+				StepOptions: StepOptions[int]{InputQueue: tt.queue},
+			})
+			sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(int) {}})
+			p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, work, sink)
+			p.Init()
+			p.Run(context.Background())
+
+			p.FeedOne(0)
+			<-blocked
+			// the queue holds 10 tokens and the rest overflow without blocking the feeding.
+			p.FeedMany([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12})
+
+			close(release)
+			p.WaitTillDone()
+			description := p.Describe()
+			p.Terminate()
+
+			if !reflect.DeepEqual(order, tt.expected) {
+				t.Errorf("expected %v to be processed, got %v", tt.expected, order)
+			}
+			if description.Steps[0].Dropped != tt.dropped {
+				t.Errorf("expected %d dropped tokens, got %d", tt.dropped, description.Steps[0].Dropped)
+			}
+		})
+	}
+
+	if !reflect.DeepEqual(diverted, []int{11, 12}) {
+		t.Errorf("expected [11 12] to be diverted, got %v", diverted)
+	}
+}
+
+func TestInputQueue_InvalidOverflow(t *testing.T) {
+	tests := []struct {
+		name  string
+		queue *InputQueueConfig[int]
+	}{
+		{"UnknownPolicy", &InputQueueConfig[int]{Overflow: 10}},
+		{"MissingDivert", &InputQueueConfig[int]{Overflow: OverflowDivert}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			builder := &Builder[int]{}
+			builder.NewStep(StepBasicConfig[int]{Process: func(i int) int { return i }, StepOptions: StepOptions[int]{InputQueue: tt.queue}})
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package pipelines
+
+import (
+	"context"
+	"fmt"
+)
+
+// bufferedStep is implemented by the steps retaining tokens across the processing of multiple inputs.
+type bufferedStep interface {
+
+	// retainedTokens returns the number of tokens retained by the step and counted in the pipeline tokens count.
+	retainedTokens() int
+
+	// clearBuffer drops all the tokens retained by the step.
+	clearBuffer()
+
+	// flush releases the tokens retained by the step at the end of the input, running its final process over them if it has one.
+	// The tokens which are not passed on or aggregated by the final process are dropped.
+	flush()
+
+	// locked calls the function with the number of retained tokens while no tokens are retained or released.
+	locked(func(retained int))
+}
+
+func (p *pipeline[I]) Reset(preserveBuffers bool) error {
+	p.replicasMutex.Lock()
+	defer p.replicasMutex.Unlock()
+
+	if state := p.State(); state != StateInitialized && state != StateTerminated {
+		return &StateError{Op: "reset", State: state}
+	}
+
+	// the discarded tokens are not acknowledged, so all the tokens which are not acknowledged are replayed by the next run.
+	p.lineages.abandon(ErrTokenAbandoned)
+	if p.wal != nil {
+		if err := p.wal.rewind(); err != nil {
+			return fmt.Errorf("rewinding write-ahead log: %w", err)
+		}
+	}
+
+	retained := 0
+	for _, step := range p.steps {
+		if buffered, ok := step.(bufferedStep); ok {
+			if preserveBuffers {
+				retained += buffered.retainedTokens()
+			} else {
+				buffered.clearBuffer()
+			}
+		}
+	}
+
+	// the tokens left in the old channels are discarded, so only the preserved tokens are counted.
+	p.tokensCountMutex.Lock()
+	p.tokensCount = 0
+	if p.trackTokensCount {
+		p.tokensCount = uint64(retained)
+	}
+	p.doneCond.Broadcast()
+	p.tokensCountMutex.Unlock()
+
+	p.connectSteps()
+	p.replicas = nil
+
+	p.setState(StateInitialized)
+	return nil
+}
+
+func (p *pipeline[I]) Restart(ctx context.Context, preserveBuffers bool) error {
+	if err := p.Reset(preserveBuffers); err != nil {
+		return err
+	}
+	return p.Run(ctx)
+}
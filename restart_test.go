@@ -0,0 +1,149 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitForResults polls the results till they reach the count. The buffer retains the tokens, so the pipeline is never done.
+func waitForResults(t *testing.T, results *atomic.Int64, count int64) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for results.Load() < count {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d results, got %d", count, results.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPipeline_Restart(t *testing.T) {
+	var results atomic.Int64
+	builder := &Builder[int]{}
+	buffer := builder.NewStep(StepBufferConfig[int]{
+		Label:       "buffer",
+		BufferSize:  5,
+		PassThrough: true,
+		InputTriggeredProcess: func([]int) (int, BufferFlags) {
+			return 0, BufferFlags{}
+		},
+	})
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(int) { results.Add(1) }})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, buffer, sink)
+	p.Init()
+	p.Run(context.Background())
+	p.FeedMany([]int{1, 2, 3})
+	waitForResults(t, &results, 3)
+	p.Terminate()
+
+	if err := p.Restart(context.Background(), false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.State() != StateRunning {
+		t.Errorf("expected state to be running, got %s", p.State())
+	}
+	if p.TokensCount() != 0 {
+		t.Errorf("expected tokens count to be 0, got %d", p.TokensCount())
+	}
+	if p.Describe().Steps[0].Buffer.Buffered != 0 {
+		t.Errorf("expected buffer to be cleared, got %d", p.Describe().Steps[0].Buffer.Buffered)
+	}
+
+	p.FeedMany([]int{4, 5})
+	waitForResults(t, &results, 5)
+	p.Terminate()
+	if p.State() != StateTerminated {
+		t.Errorf("expected state to be terminated, got %s", p.State())
+	}
+}
+
+func TestPipeline_Restart_PreserveBuffers(t *testing.T) {
+	var results atomic.Int64
+	builder := &Builder[int]{}
+	buffer := builder.NewStep(StepBufferConfig[int]{
+		Label:       "buffer",
+		BufferSize:  5,
+		PassThrough: true,
+		InputTriggeredProcess: func([]int) (int, BufferFlags) {
+			return 0, BufferFlags{}
+		},
+	})
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(int) { results.Add(1) }})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, buffer, sink)
+	p.Init()
+	p.Run(context.Background())
+	p.FeedMany([]int{1, 2, 3})
+	waitForResults(t, &results, 3)
+	p.Terminate()
+
+	if err := p.Reset(true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.State() != StateInitialized {
+		t.Errorf("expected state to be initialized, got %s", p.State())
+	}
+	if p.Describe().Steps[0].Buffer.Buffered != 3 {
+		t.Errorf("expected 3 buffered tokens to be preserved, got %d", p.Describe().Steps[0].Buffer.Buffered)
+	}
+	if p.TokensCount() != 3 {
+		t.Errorf("expected preserved tokens to be counted, got %d", p.TokensCount())
+	}
+
+	p.Run(context.Background())
+	p.FeedOne(4)
+	waitForResults(t, &results, 4)
+	if p.Describe().Steps[0].Buffer.Buffered != 4 {
+		t.Errorf("expected 4 buffered tokens, got %d", p.Describe().Steps[0].Buffer.Buffered)
+	}
+	p.Terminate()
+}
+
+func TestPipeline_Restart_ScaledReplicas(t *testing.T) {
+	builder := &Builder[int]{}
+	work := builder.NewStep(StepBasicConfig[int]{Label: "work", Replicas: 2, Process: func(i int) int { return i }})
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(int) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, work, sink)
+	p.Init()
+	p.Run(context.Background())
+	if err := p.ScaleStep("work", 4); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p.Terminate()
+
+	// the step keeps the replicas it was scaled to.
+	if err := p.Restart(context.Background(), false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer p.Terminate()
+	if replicas := p.Describe().Steps[0].Replicas; replicas != 4 {
+		t.Errorf("expected the restarted step to keep 4 replicas, got %d", replicas)
+	}
+	if replicas := p.Describe().Steps[1].Replicas; replicas != 1 {
+		t.Errorf("expected the restarted sink to keep 1 replica, got %d", replicas)
+	}
+}
+
+func TestPipeline_Restart_InvalidState(t *testing.T) {
+	builder := &Builder[int]{}
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(int) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, sink)
+
+	if err := p.Restart(context.Background(), false); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState restarting a created pipeline, got %v", err)
+	}
+
+	p.Init()
+	var stateErr *StateError
+	if err := p.Init(); !errors.As(err, &stateErr) || stateErr.Op != "init" || stateErr.State != StateInitialized {
+		t.Errorf("expected state error initializing twice, got %v", err)
+	}
+
+	p.Run(context.Background())
+	defer p.Terminate()
+	if err := p.Reset(false); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState resetting a running pipeline, got %v", err)
+	}
+}
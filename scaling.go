@@ -0,0 +1,106 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrStepNotFound is returned when no step in the pipeline has the requested label.
+var ErrStepNotFound = errors.New("step not found")
+
+// stepReplicas keeps track of the running replicas of a step.
+type stepReplicas struct {
+
+	// cancels is the list of the functions cancelling the context of each running replica.
+	cancels []context.CancelFunc
+
+	// running is used to wait for the replicas of the step to stop.
+	running sync.WaitGroup
+
+	// paused is the number of replicas stopped by pausing the pipeline.
+	paused uint16
+}
+
+// spawnReplica runs a new replica of the step at the given index. It has to be called while holding the replicas mutex.
+func (p *pipeline[I]) spawnReplica(index int) {
+	ctx, cancel := context.WithCancel(p.stepsCtx)
+	replicas := p.replicas[index]
+	replicas.cancels = append(replicas.cancels, cancel)
+	replicas.running.Add(1)
+	p.stepsWaitGroup.Add(1)
+	go func() {
+		defer p.stepsWaitGroup.Done()
+		p.steps[index].Run(ctx, &replicas.running)
+	}()
+}
+
+// retireReplica stops the most recent replica of the step at the given index. It has to be called while holding the replicas mutex.
+// The replica finishes the token it is processing before it stops, and the tokens waiting in the input channel are left for the other replicas.
+func (p *pipeline[I]) retireReplica(index int) {
+	cancels := p.replicas[index].cancels
+	last := len(cancels) - 1
+	cancels[last]()
+	p.replicas[index].cancels = cancels[:last]
+}
+
+// runningReplicas returns the number of running replicas of the step at the given index.
+func (p *pipeline[I]) runningReplicas(index int) uint16 {
+	p.replicasMutex.Lock()
+	defer p.replicasMutex.Unlock()
+	if p.replicas == nil {
+		return p.configuredReplicas(index)
+	}
+	return uint16(len(p.replicas[index].cancels))
+}
+
+// configuredReplicas returns the number of replicas the step at the given index is run with, which is the number it was
+// scaled to in the previous run if the pipeline is restarted. It has to be called while holding the replicas mutex.
+func (p *pipeline[I]) configuredReplicas(index int) uint16 {
+	if p.scaledReplicas != nil {
+		return p.scaledReplicas[index]
+	}
+	return p.steps[index].GetReplicas()
+}
+
+// stepIndex returns the index of the first step with the given label.
+func (p *pipeline[I]) stepIndex(label string) (int, error) {
+	for i, step := range p.steps {
+		if step != nil && step.GetLabel() == label {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("%w: %q", ErrStepNotFound, label)
+}
+
+func (p *pipeline[I]) ScaleStep(label string, replicas uint16) error {
+	if replicas == 0 {
+		return fmt.Errorf("replicas of step %q must be greater than 0", label)
+	}
+
+	index, err := p.stepIndex(label)
+	if err != nil {
+		return err
+	}
+
+	return p.scaleStep(index, replicas)
+}
+
+// scaleStep changes the number of running replicas of the step at the given index.
+func (p *pipeline[I]) scaleStep(index int, replicas uint16) error {
+	p.replicasMutex.Lock()
+	defer p.replicasMutex.Unlock()
+
+	if state := p.State(); state != StateRunning {
+		return &StateError{Op: "scale", State: state}
+	}
+
+	for uint16(len(p.replicas[index].cancels)) < replicas {
+		p.spawnReplica(index)
+	}
+	for uint16(len(p.replicas[index].cancels)) > replicas {
+		p.retireReplica(index)
+	}
+	return nil
+}
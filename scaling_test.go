@@ -0,0 +1,129 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPipeline_ScaleStep_Up(t *testing.T) {
+	var concurrent, maxConcurrent atomic.Int64
+	release := make(chan struct{})
+	var results atomic.Int64
+
+	builder := &Builder[int]{}
+	work := builder.NewStep(StepBasicConfig[int]{
+		Label:    "work",
+		Replicas: 1,
+		Process: func(i int) int {
+			current := concurrent.Add(1)
+			for {
+				highest := maxConcurrent.Load()
+				if current <= highest || maxConcurrent.CompareAndSwap(highest, current) {
+					break
+				}
+			}
+			<-release
+			concurrent.Add(-1)
+			return i
+		},
+	})
+	sink := builder.NewStep(StepTerminalConfig[int]{
+		Label:   "sink",
+		Process: func(int) { results.Add(1) },
+	})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, work, sink)
+	p.Init()
+	p.Run(context.Background())
+
+	if err := p.ScaleStep("work", 3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Describe().Steps[0].Replicas != 3 {
+		t.Errorf("expected 3 replicas, got %d", p.Describe().Steps[0].Replicas)
+	}
+
+	p.FeedMany([]int{1, 2, 3})
+	deadline := time.Now().Add(time.Second)
+	for concurrent.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if concurrent.Load() != 3 {
+		t.Errorf("expected 3 tokens processed concurrently, got %d", concurrent.Load())
+	}
+
+	close(release)
+	p.WaitTillDone()
+	p.Terminate()
+
+	if results.Load() != 3 {
+		t.Errorf("expected 3 results, got %d", results.Load())
+	}
+}
+
+func TestPipeline_ScaleStep_Down(t *testing.T) {
+	var results atomic.Int64
+	var mutex sync.Mutex
+
+	builder := &Builder[int]{}
+	work := builder.NewStep(StepBasicConfig[int]{
+		Label:    "work",
+		Replicas: 4,
+		Process: func(i int) int {
+			mutex.Lock()
+			defer mutex.Unlock()
+			time.Sleep(time.Millisecond)
+			return i
+		},
+	})
+	sink := builder.NewStep(StepTerminalConfig[int]{
+		Label:   "sink",
+		Process: func(int) { results.Add(1) },
+	})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, work, sink)
+	p.Init()
+	p.Run(context.Background())
+
+	for i := 0; i < 50; i++ {
+		p.FeedOne(i)
+		if i == 10 {
+			if err := p.ScaleStep("work", 1); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	}
+
+	p.WaitTillDone()
+	if results.Load() != 50 {
+		t.Errorf("expected all 50 tokens to be processed, got %d", results.Load())
+	}
+	if p.Describe().Steps[0].Replicas != 1 {
+		t.Errorf("expected 1 replica, got %d", p.Describe().Steps[0].Replicas)
+	}
+	p.Terminate()
+}
+
+func TestPipeline_ScaleStep_Errors(t *testing.T) {
+	builder := &Builder[int]{}
+	work := builder.NewStep(StepBasicConfig[int]{Label: "work", Process: func(i int) int { return i }})
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(int) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, work, sink)
+	p.Init()
+
+	if err := p.ScaleStep("work", 2); err == nil {
+		t.Error("expected error scaling a pipeline which is not running")
+	}
+
+	p.Run(context.Background())
+	defer p.Terminate()
+
+	if err := p.ScaleStep("missing", 2); !errors.Is(err, ErrStepNotFound) {
+		t.Errorf("expected ErrStepNotFound, got %v", err)
+	}
+	if err := p.ScaleStep("work", 0); err == nil {
+		t.Error("expected error scaling to 0 replicas")
+	}
+}
@@ -0,0 +1,141 @@
+package pipelines
+
+import (
+	"context"
+	"iter"
+	"slices"
+	"time"
+)
+
+// Source produces the tokens fed to the pipeline by FeedFrom.
+type Source[I any] interface {
+
+	// Produce passes the tokens to emit till the source is exhausted or the context is done. Emit blocks till the token is
+	// fed to the pipeline, which applies the backpressure of the pipeline to the source. If emit returns an error,
+	// Produce has to stop and return it.
+	Produce(ctx context.Context, emit func(I) error) error
+}
+
+// TrackedSource is implemented by the sources which follow the tokens they produce till they are done.
+// FeedFrom passes the tokens of a tracked source to the emit of ProduceTracked, which returns the handle of the fed
+// token, or nil if the token is not an envelope.
+type TrackedSource[I any] interface {
+	Source[I]
+	ProduceTracked(ctx context.Context, emit func(I) (*TokenHandle, error)) error
+}
+
+// SourceFunc is a function used as a Source.
+type SourceFunc[I any] func(ctx context.Context, emit func(I) error) error
+
+func (f SourceFunc[I]) Produce(ctx context.Context, emit func(I) error) error {
+	return f(ctx, emit)
+}
+
+// NewChannelSource creates a source producing the tokens received from the channel till it is closed.
+func NewChannelSource[I any](ch <-chan I) Source[I] {
+	if ch == nil {
+		panic("channel is required")
+	}
+	return SourceFunc[I](func(ctx context.Context, emit func(I) error) error {
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case token, ok := <-ch:
+				if !ok {
+					return nil
+				}
+				if err := emit(token); err != nil {
+					return err
+				}
+			}
+		}
+	})
+}
+
+// NewSeqSource creates a source producing the tokens of the iterator.
+func NewSeqSource[I any](seq iter.Seq[I]) Source[I] {
+	if seq == nil {
+		panic("iterator is required")
+	}
+	return SourceFunc[I](func(ctx context.Context, emit func(I) error) error {
+		var err error
+		for token := range seq {
+			if err = ctx.Err(); err != nil {
+				break
+			}
+			if err = emit(token); err != nil {
+				break
+			}
+		}
+		return err
+	})
+}
+
+// NewSliceSource creates a source producing the tokens of the slice in order.
+func NewSliceSource[I any](tokens []I) Source[I] {
+	return NewSeqSource(slices.Values(tokens))
+}
+
+// NewTickerSource creates a source producing a token generated at every tick of the interval till the context is done.
+// Ticks are skipped while the pipeline can't accept more tokens.
+func NewTickerSource[I any](interval time.Duration, generate func(time.Time) I) Source[I] {
+	if interval <= 0 {
+		panic("ticker interval must be greater than 0")
+	}
+	if generate == nil {
+		panic("generate is required")
+	}
+	return SourceFunc[I](func(ctx context.Context, emit func(I) error) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case tick := <-ticker.C:
+				if err := emit(generate(tick)); err != nil {
+					return err
+				}
+			}
+		}
+	})
+}
+
+func (p *pipeline[I]) FeedFrom(ctx context.Context, source Source[I]) error {
+	if err := p.checkFeedable(); err != nil {
+		return err
+	}
+
+	// the source is stopped once the pipeline is terminated as well.
+	sourceCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	p.replicasMutex.Lock()
+	if state := p.State(); state == StateRunning || state == StatePaused {
+		stop := context.AfterFunc(p.stepsCtx, cancel)
+		defer stop()
+	}
+	p.replicasMutex.Unlock()
+
+	var err error
+	if tracked, ok := source.(TrackedSource[I]); ok {
+		err = tracked.ProduceTracked(sourceCtx, func(item I) (*TokenHandle, error) {
+			var handle *TokenHandle
+			if headerOf(item) != nil {
+				handle = newTokenHandle()
+			}
+			if err := p.feed(sourceCtx, item, handle); err != nil {
+				return nil, err
+			}
+			return handle, nil
+		})
+	} else {
+		err = source.Produce(sourceCtx, func(item I) error {
+			return p.feed(sourceCtx, item, nil)
+		})
+	}
+	if err != nil && ctx.Err() == nil && sourceCtx.Err() != nil {
+		return &StateError{Op: "feed", State: p.State()}
+	}
+	return err
+}
@@ -0,0 +1,292 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultDirPollInterval is the interval at which the watched directory is listed if it is not set.
+const defaultDirPollInterval = time.Second
+
+// DirSourceConfig is a struct that defines the configuration for a source watching a directory for files to ingest.
+//
+// The files are moved to DoneDir once their tokens are done only if the pipeline is in envelope mode. Otherwise the
+// tokens can't be tracked, so a file is moved to DoneDir as soon as all its records are fed, and the records still in
+// the pipeline are lost if it stops.
+type DirSourceConfig[I any] struct {
+
+	// Dir is the watched directory.
+	Dir string
+
+	// Pattern is the glob pattern matched against the names of the files in the directory. It is "*" if it is not set.
+	Pattern string
+
+	// NewDecoder creates the decoder of the records of every file, so that decoders reading a header like the CSV
+	// decoder start over for every file. If the decoder returns ErrSkipRecord the record is skipped, and any other
+	// error fails the file.
+	NewDecoder func() Decoder[I]
+
+	// Delimiter separates the records. If it is not set, the records are lines ending with "\n" or "\r\n", or they are
+	// split by the decoder if it implements RecordSplitter.
+	Delimiter []byte
+
+	// MaxRecordSize is the max size of a record in bytes. It is 64KiB if it is not set.
+	// A file with a longer record fails.
+	MaxRecordSize int
+
+	// SkipEmpty skips the empty records without passing them to the decoder.
+	SkipEmpty bool
+
+	// DoneDir is the directory to which the files are moved once all their tokens are done, or once they are fed if the
+	// pipeline is not in envelope mode. It is the "done" directory inside the watched directory if it is not set.
+	// A file with the name of a file moved earlier is suffixed with a number, like "orders.1.csv".
+	DoneDir string
+
+	// FailedDir is the directory to which the files are moved if they fail to be decoded or any of their tokens is
+	// dropped. It is the "failed" directory inside the watched directory if it is not set.
+	FailedDir string
+
+	// PollInterval is the interval at which the directory is listed. It is 1 second if it is not set.
+	PollInterval time.Duration
+
+	// MinAge is the time for which a file has to be unmodified before it is ingested, so that the files still being
+	// written are skipped. It is optional, and not needed if the files are moved into the directory once written.
+	MinAge time.Duration
+
+	// OnProcessed is called once a file is moved, with nil if it is moved to DoneDir, or with the failure reason if it
+	// is moved to FailedDir. It is optional.
+	OnProcessed func(name string, err error)
+}
+
+// dirSource is a source ingesting the files of a watched directory.
+type dirSource[I any] struct {
+	config DirSourceConfig[I]
+}
+
+// NewDirSource creates a source feeding the records of the files added to a directory till the context is done.
+// The files are read in the order of their names, and moved to the done or failed directory once processed.
+// If the pipeline is in envelope mode, a file is done once all of its tokens are done, and fails if any of them is
+// dropped. If the pipeline or the source stops before the tokens of a file are done, the file is left in the directory
+// to be read again once the source is restarted.
+func NewDirSource[I any](config DirSourceConfig[I]) Source[I] {
+	if config.Dir == "" {
+		panic("directory is required")
+	}
+	if config.NewDecoder == nil {
+		panic("decoder is required")
+	}
+	if config.PollInterval < 0 || config.MaxRecordSize < 0 || config.MinAge < 0 {
+		panic("poll interval, max record size and min age can't be negative")
+	}
+	if config.Pattern == "" {
+		config.Pattern = "*"
+	}
+	if _, err := filepath.Match(config.Pattern, ""); err != nil {
+		panic(fmt.Sprintf("invalid pattern %q: %v", config.Pattern, err))
+	}
+	if config.DoneDir == "" {
+		config.DoneDir = filepath.Join(config.Dir, "done")
+	}
+	if config.FailedDir == "" {
+		config.FailedDir = filepath.Join(config.Dir, "failed")
+	}
+	if config.PollInterval == 0 {
+		config.PollInterval = defaultDirPollInterval
+	}
+	if config.MaxRecordSize == 0 {
+		config.MaxRecordSize = defaultMaxRecordSize
+	}
+	return &dirSource[I]{config: config}
+}
+
+func (s *dirSource[I]) Produce(ctx context.Context, emit func(I) error) error {
+	return s.ProduceTracked(ctx, func(token I) (*TokenHandle, error) {
+		return nil, emit(token)
+	})
+}
+
+func (s *dirSource[I]) ProduceTracked(ctx context.Context, emit func(I) (*TokenHandle, error)) error {
+	for _, dir := range []string{s.config.DoneDir, s.config.FailedDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	watch := &dirWatch[I]{config: s.config, emit: emit, inFlight: make(map[string]bool)}
+	// the source returns once the files already fed are moved, or left in the directory if their tokens are not done
+	// by the time the source stops.
+	ctx, cancel := context.WithCancel(ctx)
+	defer watch.wg.Wait()
+	defer cancel()
+
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := watch.poll(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// dirWatch keeps the state of a running directory source.
+type dirWatch[I any] struct {
+	config DirSourceConfig[I]
+	emit   func(I) (*TokenHandle, error)
+
+	// wg tracks the goroutines waiting for the tokens of the fed files to be done.
+	wg sync.WaitGroup
+
+	// mutex guards the files waiting for their tokens and the error of moving a file.
+	mutex sync.Mutex
+
+	// inFlight are the fed files waiting for their tokens to be done.
+	inFlight map[string]bool
+
+	// err is the first error of moving a file, which stops the source.
+	err error
+}
+
+// poll feeds the files in the directory which are not fed yet.
+func (w *dirWatch[I]) poll(ctx context.Context) error {
+	// the pattern is validated, so listing the directory doesn't fail.
+	paths, _ := filepath.Glob(filepath.Join(w.config.Dir, w.config.Pattern))
+	for _, path := range paths {
+		w.mutex.Lock()
+		err, inFlight := w.err, w.inFlight[path]
+		w.mutex.Unlock()
+		if err != nil {
+			return err
+		}
+		if inFlight {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || time.Since(info.ModTime()) < w.config.MinAge {
+			continue
+		}
+		if err := w.feed(ctx, path); err != nil {
+			return err
+		}
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.err
+}
+
+// feed feeds the records of the file, and moves it once its tokens are done. It returns an error only if the source
+// has to stop, in which case the file is left in the directory.
+func (w *dirWatch[I]) feed(ctx context.Context, path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		w.move(path, err)
+		return nil
+	}
+
+	var handles []*TokenHandle
+	var emitErr error
+	decoder := w.config.NewDecoder()
+	scanner := newRecordScanner(file, w.config.Delimiter, w.config.MaxRecordSize, decoder)
+	err = decodeRecordsFrom(ctx, scanner, decoder, w.config.SkipEmpty, func(token I) error {
+		handle, err := w.emit(token)
+		if handle != nil {
+			handles = append(handles, handle)
+		}
+		emitErr = err
+		return err
+	})
+	file.Close()
+	if emitErr != nil || (err != nil && ctx.Err() != nil) {
+		return err
+	}
+	if err != nil {
+		w.move(path, err)
+		return nil
+	}
+
+	w.mutex.Lock()
+	w.inFlight[path] = true
+	w.mutex.Unlock()
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.await(ctx, path, handles)
+	}()
+	return nil
+}
+
+// await waits for the tokens of the file to be done, and moves the file based on their outcome. The file is left in the
+// directory to be read again if the context is done first.
+func (w *dirWatch[I]) await(ctx context.Context, path string, handles []*TokenHandle) {
+	defer func() {
+		w.mutex.Lock()
+		delete(w.inFlight, path)
+		w.mutex.Unlock()
+	}()
+
+	dropped := 0
+	for _, handle := range handles {
+		select {
+		case <-handle.Done():
+		case <-ctx.Done():
+			return
+		}
+		if handle.Err() != nil {
+			// the pipeline stopped before the tokens were done, so the file is left to be read again.
+			return
+		}
+		dropped += handle.Outcome().Dropped
+	}
+	var err error
+	if dropped > 0 {
+		err = fmt.Errorf("%d tokens dropped", dropped)
+	}
+	w.move(path, err)
+}
+
+// move moves the file to the done directory, or to the failed directory if it failed with the given error.
+func (w *dirWatch[I]) move(path string, failure error) {
+	dir := w.config.DoneDir
+	if failure != nil {
+		dir = w.config.FailedDir
+	}
+	if err := os.Rename(path, targetPath(dir, filepath.Base(path))); err != nil {
+		w.mutex.Lock()
+		if w.err == nil {
+			w.err = fmt.Errorf("moving processed file: %w", err)
+		}
+		w.mutex.Unlock()
+		return
+	}
+	if w.config.OnProcessed != nil {
+		w.config.OnProcessed(filepath.Base(path), failure)
+	}
+}
+
+// targetPath returns the path of the file in the directory. It is suffixed with a number if a file with the same name
+// was already moved to the directory, so that the files ingested earlier are not overwritten.
+func targetPath(dir, name string) string {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	target := filepath.Join(dir, name)
+	for n := 1; ; n++ {
+		if _, err := os.Lstat(target); err != nil {
+			return target
+		}
+		target = filepath.Join(dir, fmt.Sprintf("%s.%d%s", base, n, ext))
+	}
+}
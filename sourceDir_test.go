@@ -0,0 +1,231 @@
+package pipelines
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// processedFiles records the files moved by a directory source.
+type processedFiles struct {
+	mutex sync.Mutex
+	files map[string]error
+}
+
+func (f *processedFiles) record(name string, err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.files == nil {
+		f.files = make(map[string]error)
+	}
+	f.files[name] = err
+}
+
+// waitFor waits till the given number of files are moved, and returns them.
+func (f *processedFiles) waitFor(t *testing.T, count int) map[string]error {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		f.mutex.Lock()
+		if len(f.files) >= count {
+			defer f.mutex.Unlock()
+			return f.files
+		}
+		f.mutex.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d processed files", count)
+	return nil
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPipeline_FeedFrom_Dir(t *testing.T) {
+	var sum atomic.Int64
+	builder := &Builder[csvRecord]{}
+	sink := builder.NewStep(StepTerminalConfig[csvRecord]{Process: func(r csvRecord) { sum.Add(int64(r.Count)) }})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, sink)
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.csv":   "name,count\na,1\nb,2\n",
+		"b.csv":   "count,name\n3,c\n",
+		"bad.csv": "name,count\nd,many\n",
+		"c.txt":   "name,count\ne,100\n",
+	})
+
+	processed := &processedFiles{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- p.FeedFrom(ctx, NewDirSource(DirSourceConfig[csvRecord]{
+			Dir:          dir,
+			Pattern:      "*.csv",
+			NewDecoder:   func() Decoder[csvRecord] { return NewCSVDecoder[csvRecord](CSVConfig{}) },
+			PollInterval: 5 * time.Millisecond,
+			OnProcessed:  processed.record,
+		}))
+	}()
+
+	files := processed.waitFor(t, 3)
+	if files["a.csv"] != nil || files["b.csv"] != nil || files["bad.csv"] == nil {
+		t.Errorf("unexpected processed files: %v", files)
+	}
+	for _, path := range []string{"done/a.csv", "done/b.csv", "failed/bad.csv", "c.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Errorf("expected %s to exist: %v", path, err)
+		}
+	}
+
+	// the files added later are ingested as well.
+	writeFiles(t, dir, map[string]string{"d.csv": "name,count\nf,4\n"})
+	processed.waitFor(t, 4)
+	cancel()
+	<-done
+	// without envelopes the files are moved once they are fed.
+	p.WaitTillDone()
+	if sum.Load() != 10 {
+		t.Errorf("expected sum to be 10, got %d", sum.Load())
+	}
+}
+
+func TestPipeline_FeedFrom_Dir_Tracked(t *testing.T) {
+	builder := &Builder[*Envelope[string]]{}
+	// only the first token passes the limiter and the rest are dropped.
+	limiter := builder.NewStep(StepRateLimiterConfig[*Envelope[string]]{Rate: 0.001, Excess: RateLimitDrop})
+	sink := builder.NewStep(StepTerminalConfig[*Envelope[string]]{Process: func(*Envelope[string]) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, limiter, sink)
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.log": "1\n2\n3\n"})
+
+	processed := &processedFiles{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.FeedFrom(ctx, NewDirSource(DirSourceConfig[*Envelope[string]]{
+		Dir: dir,
+		NewDecoder: func() Decoder[*Envelope[string]] {
+			return DecoderFunc[*Envelope[string]](func(record []byte) (*Envelope[string], error) {
+				return NewEnvelope(string(record)), nil
+			})
+		},
+		PollInterval: 5 * time.Millisecond,
+		OnProcessed:  processed.record,
+	}))
+
+	files := processed.waitFor(t, 1)
+	if err := files["a.log"]; err == nil || !strings.Contains(err.Error(), "2 tokens dropped") {
+		t.Errorf("expected the file to fail with 2 dropped tokens, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "failed", "a.log")); err != nil {
+		t.Errorf("expected the file to be moved to the failed directory: %v", err)
+	}
+}
+
+func TestPipeline_FeedFrom_Dir_CSVEnvelopes(t *testing.T) {
+	var sum atomic.Int64
+	builder := &Builder[*Envelope[csvRecord]]{}
+	sink := builder.NewStep(StepTerminalConfig[*Envelope[csvRecord]]{Process: func(e *Envelope[csvRecord]) { sum.Add(int64(e.Value.Count)) }})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, sink)
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+
+	dir := t.TempDir()
+	// the quoted new line does not split the record, since the decoder splits the records in envelope mode as well.
+	writeFiles(t, dir, map[string]string{"a.csv": "name,count\n\"a\nb\",1\nc,2\n"})
+
+	processed := &processedFiles{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.FeedFrom(ctx, NewDirSource(DirSourceConfig[*Envelope[csvRecord]]{
+		Dir:          dir,
+		NewDecoder:   func() Decoder[*Envelope[csvRecord]] { return NewCSVDecoder[*Envelope[csvRecord]](CSVConfig{}) },
+		PollInterval: 5 * time.Millisecond,
+		OnProcessed:  processed.record,
+	}))
+
+	// in envelope mode the file is moved once its tokens are done.
+	files := processed.waitFor(t, 1)
+	if err := files["a.csv"]; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if sum.Load() != 3 {
+		t.Errorf("expected sum to be 3, got %d", sum.Load())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "done", "a.csv")); err != nil {
+		t.Errorf("expected the file to be moved to the done directory: %v", err)
+	}
+}
+
+func TestTargetPath(t *testing.T) {
+	dir := t.TempDir()
+	if path := targetPath(dir, "a.csv"); path != filepath.Join(dir, "a.csv") {
+		t.Errorf("expected the name to be kept, got %s", path)
+	}
+	// the files moved earlier with the same name are not overwritten.
+	writeFiles(t, dir, map[string]string{"a.csv": "", "a.1.csv": ""})
+	if path := targetPath(dir, "a.csv"); path != filepath.Join(dir, "a.2.csv") {
+		t.Errorf("expected a.2.csv, got %s", path)
+	}
+}
+
+func TestPipeline_FeedFrom_Dir_Cancelled(t *testing.T) {
+	builder := &Builder[*Envelope[string]]{}
+	// the buffer retains the tokens and never flushes them.
+	buffer := builder.NewStep(StepBufferConfig[*Envelope[string]]{
+		BufferSize:            10,
+		InputTriggeredProcess: func([]*Envelope[string]) (*Envelope[string], BufferFlags) { return nil, BufferFlags{} },
+	})
+	sink := builder.NewStep(StepTerminalConfig[*Envelope[string]]{Process: func(*Envelope[string]) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, buffer, sink)
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.log": "1\n2\n"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- p.FeedFrom(ctx, NewDirSource(DirSourceConfig[*Envelope[string]]{
+			Dir: dir,
+			NewDecoder: func() Decoder[*Envelope[string]] {
+				return DecoderFunc[*Envelope[string]](func(record []byte) (*Envelope[string], error) {
+					return NewEnvelope(string(record)), nil
+				})
+			},
+			PollInterval: 5 * time.Millisecond,
+		}))
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the source to return once its context is cancelled")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.log")); err != nil {
+		t.Errorf("expected the unfinished file to be left in the directory: %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package pipelines
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// defaultMaxRecordSize is the max size of a record read by the reader source if it is not set.
+const defaultMaxRecordSize = 64 * 1024
+
+// ReaderSourceConfig is a struct that defines the configuration for a source reading delimited records from a reader.
+type ReaderSourceConfig[I any] struct {
+
+	// Reader is the reader from which the records are read till its end.
+	Reader io.Reader
+
+	// Delimiter separates the records. If it is not set, the records are lines ending with "\n" or "\r\n", or they are
+	// split by the decoder if it implements RecordSplitter.
+	Delimiter []byte
+
+	// MaxRecordSize is the max size of a record in bytes. It is 64KiB if it is not set.
+	// Reading a longer record stops the source with bufio.ErrTooLong.
+	MaxRecordSize int
+
+	// Decoder decodes every record into a token. If it returns ErrSkipRecord the record is skipped, and any other error
+	// stops the source.
+	Decoder Decoder[I]
+
+	// SkipEmpty skips the empty records without passing them to the decoder.
+	SkipEmpty bool
+}
+
+// NewReaderSource creates a source producing a token decoded from every record read from the reader.
+// The context is checked between the records, so a read blocked on the reader isn't interrupted by it.
+func NewReaderSource[I any](config ReaderSourceConfig[I]) Source[I] {
+	if config.Reader == nil {
+		panic("reader is required")
+	}
+	if config.Decoder == nil {
+		panic("decoder is required")
+	}
+	if config.MaxRecordSize < 0 {
+		panic("max record size can't be negative")
+	}
+	if config.MaxRecordSize == 0 {
+		config.MaxRecordSize = defaultMaxRecordSize
+	}
+	return SourceFunc[I](func(ctx context.Context, emit func(I) error) error {
+		scanner := newRecordScanner(config.Reader, config.Delimiter, config.MaxRecordSize, config.Decoder)
+		return decodeRecordsFrom(ctx, scanner, config.Decoder, config.SkipEmpty, emit)
+	})
+}
+
+// newRecordScanner creates a scanner splitting the records at the delimiter. If it is not set, the records are split by
+// the decoder if it is a RecordSplitter, or at the line ends otherwise.
+func newRecordScanner[I any](reader io.Reader, delimiter []byte, maxRecordSize int, decoder Decoder[I]) *bufio.Scanner {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, min(4096, maxRecordSize)), maxRecordSize)
+	if len(delimiter) > 0 {
+		scanner.Split(splitDelimiter(delimiter))
+	} else if splitter, ok := decoder.(RecordSplitter); ok {
+		scanner.Split(splitter.SplitRecords())
+	}
+	return scanner
+}
+
+// decodeRecordsFrom decodes the records scanned by the scanner and passes the tokens to emit.
+func decodeRecordsFrom[I any](ctx context.Context, scanner *bufio.Scanner, decoder Decoder[I], skipEmpty bool, emit func(I) error) error {
+	for n := 1; scanner.Scan(); n++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if skipEmpty && len(scanner.Bytes()) == 0 {
+			continue
+		}
+		// the scanner reuses its buffer, so the decoder gets a copy which it can retain.
+		token, err := decoder.Decode(bytes.Clone(scanner.Bytes()))
+		if errors.Is(err, ErrSkipRecord) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("decoding record %d: %w", n, err)
+		}
+		if err := emit(token); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading records: %w", err)
+	}
+	return nil
+}
+
+// splitDelimiter creates a split function for a scanner splitting the records at the delimiter.
+func splitDelimiter(delimiter []byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+		if i := bytes.Index(data, delimiter); i >= 0 {
+			return i + len(delimiter), data[:i], nil
+		}
+		if atEOF {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
@@ -0,0 +1,100 @@
+package pipelines
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// collectSource produces all the tokens of the source into a slice.
+func collectSource[I any](source Source[I]) ([]I, error) {
+	var tokens []I
+	err := source.Produce(context.Background(), func(token I) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+	return tokens, err
+}
+
+var atoiDecoder = DecoderFunc[int](func(record []byte) (int, error) {
+	return strconv.Atoi(string(record))
+})
+
+func TestReaderSource_Lines(t *testing.T) {
+	source := NewReaderSource(ReaderSourceConfig[int]{
+		Reader:    strings.NewReader("1\r\n2\n\n3"),
+		Decoder:   atoiDecoder,
+		SkipEmpty: true,
+	})
+	tokens, err := collectSource(source)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(tokens, []int{1, 2, 3}) {
+		t.Errorf("expected [1 2 3], got %v", tokens)
+	}
+}
+
+func TestReaderSource_Delimiter(t *testing.T) {
+	source := NewReaderSource(ReaderSourceConfig[int]{
+		Reader:    strings.NewReader("1||2||x||3||"),
+		Delimiter: []byte("||"),
+		Decoder: DecoderFunc[int](func(record []byte) (int, error) {
+			if string(record) == "x" {
+				return 0, ErrSkipRecord
+			}
+			return strconv.Atoi(string(record))
+		}),
+	})
+	tokens, err := collectSource(source)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(tokens, []int{1, 2, 3}) {
+		t.Errorf("expected [1 2 3], got %v", tokens)
+	}
+}
+
+func TestReaderSource_Errors(t *testing.T) {
+	_, err := collectSource(NewReaderSource(ReaderSourceConfig[int]{
+		Reader:  strings.NewReader("1\nx\n3"),
+		Decoder: atoiDecoder,
+	}))
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) || !strings.Contains(err.Error(), "record 2") {
+		t.Errorf("expected the decoding error of record 2, got %v", err)
+	}
+
+	_, err = collectSource(NewReaderSource(ReaderSourceConfig[int]{
+		Reader:        strings.NewReader("1\n" + strings.Repeat("9", 100) + "\n"),
+		Decoder:       atoiDecoder,
+		MaxRecordSize: 10,
+	}))
+	if !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("expected bufio.ErrTooLong, got %v", err)
+	}
+}
+
+func TestPipeline_FeedFrom_Reader(t *testing.T) {
+	var sum atomic.Int64
+	builder := &Builder[int]{}
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(i int) { sum.Add(int64(i)) }})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 1, TrackTokensCount: true}, sink)
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+
+	source := NewReaderSource(ReaderSourceConfig[int]{Reader: strings.NewReader("1\n2\n3\n"), Decoder: atoiDecoder})
+	if err := p.FeedFrom(context.Background(), source); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p.WaitTillDone()
+	if sum.Load() != 6 {
+		t.Errorf("expected sum to be 6, got %d", sum.Load())
+	}
+}
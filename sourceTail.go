@@ -0,0 +1,336 @@
+package pipelines
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// defaultTailPollInterval is the interval at which the tailed file is checked if it is not set.
+const defaultTailPollInterval = 250 * time.Millisecond
+
+// TailSourceConfig is a struct that defines the configuration for a source tailing the lines appended to a file.
+type TailSourceConfig[I any] struct {
+
+	// Path is the path of the tailed file. The source waits for the file if it doesn't exist.
+	Path string
+
+	// Decoder decodes every line into a token. If it returns ErrSkipRecord the line is skipped, and any other error
+	// stops the source. The records are always split at the line ends, so CSV records with quoted new lines can't be tailed.
+	Decoder Decoder[I]
+
+	// StateFile is the path of the file in which the offset of the lines fed to the pipeline is saved, so that the
+	// source resumes from it when it is restarted. It is optional.
+	StateFile string
+
+	// FromStart makes the source read the file from its start if there is no saved offset. By default only the lines
+	// appended after the source is started are read.
+	FromStart bool
+
+	// PollInterval is the interval at which the file is checked for appends, truncation and rotation. It is 250ms if
+	// it is not set.
+	PollInterval time.Duration
+
+	// MaxRecordSize is the max size of a line in bytes. It is 64KiB if it is not set.
+	// Reading a longer line stops the source with bufio.ErrTooLong.
+	MaxRecordSize int
+
+	// SkipEmpty skips the empty lines without passing them to the decoder.
+	SkipEmpty bool
+}
+
+// NewTailSource creates a source following the lines appended to a file till the context is done, like `tail -F`.
+// A line is read only once it is terminated by a new line. If the file is truncated it is read again from its start,
+// and if it is rotated by renaming it, the rest of the old file is read before following the new file.
+func NewTailSource[I any](config TailSourceConfig[I]) Source[I] {
+	if config.Path == "" {
+		panic("path is required")
+	}
+	if config.Decoder == nil {
+		panic("decoder is required")
+	}
+	if config.PollInterval < 0 || config.MaxRecordSize < 0 {
+		panic("poll interval and max record size can't be negative")
+	}
+	if config.PollInterval == 0 {
+		config.PollInterval = defaultTailPollInterval
+	}
+	if config.MaxRecordSize == 0 {
+		config.MaxRecordSize = defaultMaxRecordSize
+	}
+	return SourceFunc[I](func(ctx context.Context, emit func(I) error) error {
+		tail := &fileTail[I]{config: config, emit: emit, chunk: make([]byte, 32*1024), fromStart: config.FromStart}
+		return tail.run(ctx)
+	})
+}
+
+// tailState is the offset saved in the state file of the tail source.
+type tailState struct {
+
+	// ID identifies the tailed file to detect if it was rotated while the source was stopped. It is 0 if it is unknown.
+	ID uint64 `json:"id"`
+
+	// Offset is the offset of the end of the last line fed to the pipeline.
+	Offset int64 `json:"offset"`
+}
+
+// fileTail keeps the state of a running tail source.
+type fileTail[I any] struct {
+	config TailSourceConfig[I]
+	emit   func(I) error
+
+	// file is the tailed file, and info is its info when it was opened. It is nil till the file is opened.
+	file *os.File
+	info os.FileInfo
+
+	// offset is the offset of the end of the last line fed to the pipeline.
+	offset int64
+
+	// pending are the bytes read after the offset which don't form a complete line yet.
+	pending []byte
+	chunk   []byte
+
+	// resume is the state loaded from the state file, used when the file is first opened.
+	resume *tailState
+
+	// saved is the last state saved to the state file.
+	saved tailState
+
+	// fromStart is set if the next opened file is read from its start, like the files created after the source started.
+	fromStart bool
+}
+
+func (t *fileTail[I]) run(ctx context.Context) error {
+	defer func() {
+		if t.file != nil {
+			t.file.Close()
+		}
+	}()
+	if err := t.loadState(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(t.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		err := t.poll(ctx)
+		// the offset is saved even if the source stops, since it is the offset of the lines already fed.
+		if saveErr := t.saveState(); err == nil {
+			err = saveErr
+		}
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll reads the lines appended to the file, and handles its truncation and rotation.
+func (t *fileTail[I]) poll(ctx context.Context) error {
+	if t.file == nil {
+		if opened, err := t.open(); err != nil || !opened {
+			return err
+		}
+	}
+	if err := t.read(ctx, false); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(t.config.Path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		// the file is rotated and the new file is not created yet.
+		return nil
+	case err != nil:
+		return err
+	case !os.SameFile(info, t.info):
+		// the file is rotated, so the rest of the old file is read including its last unterminated line.
+		if err := t.read(ctx, true); err != nil {
+			return err
+		}
+		t.file.Close()
+		t.file = nil
+		return t.poll(ctx)
+	case info.Size() < t.offset+int64(len(t.pending)):
+		// the file is truncated.
+		if _, err := t.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		t.offset = 0
+		t.pending = t.pending[:0]
+		return t.read(ctx, false)
+	}
+	return nil
+}
+
+// open opens the file at the offset from which it is read. It returns false if the file doesn't exist yet.
+func (t *fileTail[I]) open() (bool, error) {
+	file, err := os.Open(t.config.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.fromStart = true
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return false, err
+	}
+
+	var offset int64
+	switch {
+	case t.resume != nil:
+		// the saved offset is used only if the file was not rotated or truncated while the source was stopped.
+		if t.resume.ID == fileID(info) && t.resume.Offset <= info.Size() {
+			offset = t.resume.Offset
+		}
+	case !t.fromStart:
+		offset = info.Size()
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return false, err
+	}
+
+	t.file, t.info, t.offset = file, info, offset
+	t.pending = t.pending[:0]
+	// the saved offset is of the first opened file, and the files created after rotation are read from their start.
+	t.resume, t.fromStart = nil, true
+	return true, nil
+}
+
+// read reads the file till its end and feeds the complete lines. If final is set, the last line is fed even if it is
+// not terminated, since the file is not appended anymore.
+func (t *fileTail[I]) read(ctx context.Context, final bool) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := t.file.Read(t.chunk)
+		if n > 0 {
+			t.pending = append(t.pending, t.chunk[:n]...)
+			if err := t.consume(); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if final && len(t.pending) > 0 {
+		if err := t.feed(t.pending, len(t.pending)); err != nil {
+			return err
+		}
+		t.pending = t.pending[:0]
+	}
+	return nil
+}
+
+// consume feeds the complete lines in the pending bytes, and keeps the rest.
+func (t *fileTail[I]) consume() error {
+	rest := t.pending
+	for {
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 {
+			break
+		}
+		if err := t.feed(rest[:i], i+1); err != nil {
+			t.pending = append(t.pending[:0], rest...)
+			return err
+		}
+		rest = rest[i+1:]
+	}
+	t.pending = append(t.pending[:0], rest...)
+	if len(t.pending) > t.config.MaxRecordSize {
+		return fmt.Errorf("reading line at offset %d: %w", t.offset, bufio.ErrTooLong)
+	}
+	return nil
+}
+
+// feed decodes the line and feeds its token, and moves the offset past the line of the given size once it is fed.
+func (t *fileTail[I]) feed(line []byte, size int) error {
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	if !t.config.SkipEmpty || len(line) > 0 {
+		// the pending bytes are reused, so the decoder gets a copy which it can retain.
+		token, err := t.config.Decoder.Decode(bytes.Clone(line))
+		if err != nil && !errors.Is(err, ErrSkipRecord) {
+			return fmt.Errorf("decoding line at offset %d: %w", t.offset, err)
+		}
+		if err == nil {
+			if err := t.emit(token); err != nil {
+				return err
+			}
+		}
+	}
+	t.offset += int64(size)
+	return nil
+}
+
+// loadState loads the offset saved in the state file if it exists.
+func (t *fileTail[I]) loadState() error {
+	if t.config.StateFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(t.config.StateFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("loading tail state: %w", err)
+	}
+	var state tailState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("loading tail state: %w", err)
+	}
+	t.resume, t.saved = &state, state
+	return nil
+}
+
+// saveState saves the offset to the state file if it changed. The file is replaced atomically by renaming.
+func (t *fileTail[I]) saveState() error {
+	if t.config.StateFile == "" || t.file == nil {
+		return nil
+	}
+	state := tailState{ID: fileID(t.info), Offset: t.offset}
+	if state == t.saved {
+		return nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("saving tail state: %w", err)
+	}
+	temp, err := os.CreateTemp(filepath.Dir(t.config.StateFile), filepath.Base(t.config.StateFile)+".tmp")
+	if err != nil {
+		return fmt.Errorf("saving tail state: %w", err)
+	}
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), t.config.StateFile)
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+		return fmt.Errorf("saving tail state: %w", err)
+	}
+	t.saved = state
+	return nil
+}
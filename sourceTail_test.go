@@ -0,0 +1,181 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// runTail runs the tail source in the background and passes its tokens to the returned channel.
+func runTail(t *testing.T, config TailSourceConfig[int]) (<-chan int, func() error) {
+	t.Helper()
+	config.Decoder = atoiDecoder
+	config.PollInterval = 5 * time.Millisecond
+	tokens := make(chan int, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- NewTailSource(config).Produce(ctx, func(i int) error {
+			tokens <- i
+			return nil
+		})
+	}()
+	stop := sync.OnceValue(func() error {
+		cancel()
+		return <-done
+	})
+	t.Cleanup(func() { stop() })
+	return tokens, stop
+}
+
+// expectTokens fails the test unless the tokens are received in order.
+func expectTokens(t *testing.T, tokens <-chan int, expected ...int) {
+	t.Helper()
+	for _, want := range expected {
+		select {
+		case got := <-tokens:
+			if got != want {
+				t.Fatalf("expected %d, got %d", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %d", want)
+		}
+	}
+	select {
+	case got := <-tokens:
+		t.Fatalf("unexpected token %d", got)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTailSource_Appends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "1\n2\n")
+
+	tokens, _ := runTail(t, TailSourceConfig[int]{Path: path, FromStart: true})
+	expectTokens(t, tokens, 1, 2)
+
+	// the unterminated line is read once it is terminated.
+	appendFile(t, path, "3\r\n4")
+	expectTokens(t, tokens, 3)
+	appendFile(t, path, "\n")
+	expectTokens(t, tokens, 4)
+}
+
+func TestTailSource_FromEnd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "1\n2\n")
+
+	tokens, _ := runTail(t, TailSourceConfig[int]{Path: path})
+	expectTokens(t, tokens)
+	appendFile(t, path, "3\n")
+	expectTokens(t, tokens, 3)
+}
+
+func TestTailSource_Truncation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "10\n20\n")
+
+	tokens, _ := runTail(t, TailSourceConfig[int]{Path: path, FromStart: true})
+	expectTokens(t, tokens, 10, 20)
+
+	if err := os.WriteFile(path, []byte("3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expectTokens(t, tokens, 3)
+}
+
+func TestTailSource_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	// the source waits for the file to be created.
+	tokens, _ := runTail(t, TailSourceConfig[int]{Path: path})
+	time.Sleep(20 * time.Millisecond)
+	appendFile(t, path, "1\n")
+	expectTokens(t, tokens, 1)
+
+	appendFile(t, path, "2\n3")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	appendFile(t, path, "4\n")
+	expectTokens(t, tokens, 2, 3, 4)
+}
+
+func TestTailSource_Resume(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	state := filepath.Join(dir, "app.state")
+	appendFile(t, path, "1\n2\n")
+
+	tokens, stop := runTail(t, TailSourceConfig[int]{Path: path, StateFile: state, FromStart: true})
+	expectTokens(t, tokens, 1, 2)
+	if err := stop(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	appendFile(t, path, "3\n")
+	tokens, _ = runTail(t, TailSourceConfig[int]{Path: path, StateFile: state, FromStart: true})
+	expectTokens(t, tokens, 3)
+}
+
+func TestTailSource_Errors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "1\nx\n")
+
+	source := NewTailSource(TailSourceConfig[int]{Path: path, FromStart: true, Decoder: atoiDecoder})
+	var tokens []int
+	err := source.Produce(context.Background(), func(i int) error {
+		tokens = append(tokens, i)
+		return nil
+	})
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) || len(tokens) != 1 {
+		t.Errorf("expected the decoding error after the first token, got %v and %v", err, tokens)
+	}
+}
+
+func TestPipeline_FeedFrom_Tail(t *testing.T) {
+	var sum atomic.Int64
+	builder := &Builder[int]{}
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(i int) { sum.Add(int64(i)) }})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 1, TrackTokensCount: true}, sink)
+	p.Init()
+	p.Run(context.Background())
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "1\n2\n3\n")
+	done := make(chan error)
+	go func() {
+		done <- p.FeedFrom(context.Background(), NewTailSource(TailSourceConfig[int]{Path: path, FromStart: true, Decoder: atoiDecoder}))
+	}()
+	for sum.Load() != 6 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the source is stopped once the pipeline is terminated.
+	p.Terminate()
+	if err := <-done; !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState, got %v", err)
+	}
+}
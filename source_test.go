@@ -0,0 +1,110 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPipeline_FeedFrom(t *testing.T) {
+	ch := make(chan int, 3)
+	ch <- 1
+	ch <- 2
+	ch <- 3
+	close(ch)
+
+	tests := map[string]Source[int]{
+		"channel": NewChannelSource(ch),
+		"seq":     NewSeqSource(slices.Values([]int{1, 2, 3})),
+		"slice":   NewSliceSource([]int{1, 2, 3}),
+	}
+	for name, source := range tests {
+		t.Run(name, func(t *testing.T) {
+			var sum atomic.Int64
+			builder := &Builder[int]{}
+			sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(i int) { sum.Add(int64(i)) }})
+			p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 1, TrackTokensCount: true}, sink)
+			p.Init()
+			p.Run(context.Background())
+			defer p.Terminate()
+
+			if err := p.FeedFrom(context.Background(), source); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			p.WaitTillDone()
+			if sum.Load() != 6 {
+				t.Errorf("expected sum to be 6, got %d", sum.Load())
+			}
+		})
+	}
+}
+
+func TestPipeline_FeedFrom_Ticker(t *testing.T) {
+	var sum atomic.Int64
+	builder := &Builder[int]{}
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(i int) { sum.Add(int64(i)) }})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 1, TrackTokensCount: true}, sink)
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := p.FeedFrom(ctx, NewTickerSource(5*time.Millisecond, func(time.Time) int { return 1 }))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	p.WaitTillDone()
+	if sum.Load() == 0 {
+		t.Errorf("expected tokens to be generated")
+	}
+}
+
+func TestPipeline_FeedFrom_Terminated(t *testing.T) {
+	var sum atomic.Int64
+	builder := &Builder[int]{}
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(i int) { sum.Add(int64(i)) }})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 1, TrackTokensCount: true}, sink)
+	p.Init()
+	p.Run(context.Background())
+
+	done := make(chan error)
+	go func() {
+		done <- p.FeedFrom(context.Background(), NewChannelSource(make(chan int)))
+	}()
+	time.Sleep(10 * time.Millisecond)
+	p.Terminate()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrInvalidState) {
+			t.Errorf("expected ErrInvalidState, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected the source to be stopped once the pipeline is terminated")
+	}
+}
+
+func TestPipeline_FeedFrom_SourceError(t *testing.T) {
+	var sum atomic.Int64
+	builder := &Builder[int]{}
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(i int) { sum.Add(int64(i)) }})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 1, TrackTokensCount: true}, sink)
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+
+	errSource := errors.New("source failed")
+	source := SourceFunc[int](func(ctx context.Context, emit func(int) error) error {
+		if err := emit(1); err != nil {
+			return err
+		}
+		return errSource
+	})
+	if err := p.FeedFrom(context.Background(), source); !errors.Is(err, errSource) {
+		t.Errorf("expected the source error, got %v", err)
+	}
+}
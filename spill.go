@@ -0,0 +1,173 @@
+package pipelines
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// errSpillFull is returned when writing a token would exceed the disk budget of the spill file.
+var errSpillFull = errors.New("spill disk budget exceeded")
+
+// SpillConfig is the configuration of spilling the tokens of a full input queue to disk.
+type SpillConfig[I any] struct {
+
+	// Dir is the directory in which the spill file is created. The default directory for temporary files is used if it is not set.
+	Dir string
+
+	// Codec is used to encode the spilled tokens and decode them back.
+	Codec Codec[I]
+
+	// MaxBytes is the max size of the spill file. Once it is reached, the overflow policy of the queue is applied
+	// till half of the file is read back, and the file is compacted.
+	MaxBytes int64
+}
+
+// validateSpillConfig panics if the spill configuration is not valid.
+func validateSpillConfig[I any](config *SpillConfig[I]) {
+	if config.Codec == nil {
+		panic("spill codec is required")
+	}
+	if config.MaxBytes <= 0 {
+		panic("spill max bytes must be greater than 0")
+	}
+}
+
+// spillFile is a FIFO of encoded tokens stored in a file. It is used by a single goroutine except for reading the count.
+type spillFile[I any] struct {
+	config SpillConfig[I]
+
+	// file is created on the first write and removed on close.
+	file *os.File
+
+	// readOffset and writeOffset are the positions of the next read and write in the file.
+	readOffset  int64
+	writeOffset int64
+
+	// count is the number of tokens in the file.
+	count atomic.Int64
+
+	// full indicates that a write exceeded the disk budget. It is cleared once enough tokens are read to compact the file.
+	full bool
+}
+
+func newSpillFile[I any](config SpillConfig[I]) *spillFile[I] {
+	return &spillFile[I]{config: config}
+}
+
+func (f *spillFile[I]) empty() bool {
+	return f.count.Load() == 0
+}
+
+// write encodes the token and appends it to the file.
+func (f *spillFile[I]) write(token I) error {
+	data, err := f.config.Codec.Encode(token)
+	if err != nil {
+		return fmt.Errorf("encoding spilled token: %w", err)
+	}
+	frame := binary.AppendUvarint(nil, uint64(len(data)))
+	frame = append(frame, data...)
+	if f.writeOffset+int64(len(frame)) > f.config.MaxBytes && f.compactable() {
+		if err := f.compact(); err != nil {
+			return err
+		}
+	}
+	if f.writeOffset+int64(len(frame)) > f.config.MaxBytes {
+		f.full = true
+		return errSpillFull
+	}
+	if f.file == nil {
+		if f.file, err = os.CreateTemp(f.config.Dir, "pipeline-spill-*"); err != nil {
+			return fmt.Errorf("creating spill file: %w", err)
+		}
+	}
+	if _, err := f.file.WriteAt(frame, f.writeOffset); err != nil {
+		return fmt.Errorf("writing spill file: %w", err)
+	}
+	f.writeOffset += int64(len(frame))
+	f.count.Add(1)
+	return nil
+}
+
+// read removes the oldest token from the file and decodes it. The file is truncated once it is drained.
+// It returns the number of tokens lost with the error. If the file can't be read, all the tokens in it are lost.
+func (f *spillFile[I]) read() (I, int, error) {
+	var token I
+	header := make([]byte, binary.MaxVarintLen64)
+	n, err := f.file.ReadAt(header, f.readOffset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return token, f.discard(), fmt.Errorf("reading spill file: %w", err)
+	}
+	size, headerSize := binary.Uvarint(header[:n])
+	// the size is checked against the bytes written before allocating, since the file can be corrupted.
+	available := f.writeOffset - f.readOffset - int64(headerSize)
+	if headerSize <= 0 || available < 0 || size > uint64(available) {
+		return token, f.discard(), fmt.Errorf("reading spill file: corrupted record at offset %d", f.readOffset)
+	}
+	data := make([]byte, size)
+	if _, err := f.file.ReadAt(data, f.readOffset+int64(headerSize)); err != nil {
+		return token, f.discard(), fmt.Errorf("reading spill file: %w", err)
+	}
+	f.readOffset += int64(headerSize) + int64(size)
+	if f.count.Add(-1) == 0 {
+		f.readOffset, f.writeOffset = 0, 0
+		f.full = false
+		// the file has no tokens left, so it is removed and created again by the next write if it can't be truncated.
+		if err := f.file.Truncate(0); err != nil {
+			f.close()
+		}
+	} else if f.full && f.compactable() {
+		f.full = false
+	}
+	token, err = f.config.Codec.Decode(data)
+	if err != nil {
+		return token, 1, fmt.Errorf("decoding spilled token: %w", err)
+	}
+	return token, 0, nil
+}
+
+// compactable checks if at least half of the file is taken by the tokens already read, so that compacting it is worth
+// copying the tokens left.
+func (f *spillFile[I]) compactable() bool {
+	return f.readOffset > 0 && f.readOffset >= f.writeOffset-f.readOffset
+}
+
+// compact moves the tokens left to the start of the file and truncates it, so that the space of the tokens already
+// read is reused before the file is drained.
+func (f *spillFile[I]) compact() error {
+	left := make([]byte, f.writeOffset-f.readOffset)
+	if _, err := f.file.ReadAt(left, f.readOffset); err != nil {
+		return fmt.Errorf("compacting spill file: %w", err)
+	}
+	if _, err := f.file.WriteAt(left, 0); err != nil {
+		return fmt.Errorf("compacting spill file: %w", err)
+	}
+	if err := f.file.Truncate(int64(len(left))); err != nil {
+		return fmt.Errorf("compacting spill file: %w", err)
+	}
+	f.readOffset, f.writeOffset = 0, int64(len(left))
+	return nil
+}
+
+// discard closes the file and returns the number of tokens dropped.
+func (f *spillFile[I]) discard() int {
+	count := int(f.count.Load())
+	f.close()
+	return count
+}
+
+// close removes the file dropping the tokens in it.
+func (f *spillFile[I]) close() {
+	if f.file == nil {
+		return
+	}
+	f.file.Close()
+	os.Remove(f.file.Name())
+	f.file = nil
+	f.readOffset, f.writeOffset = 0, 0
+	f.count.Store(0)
+	f.full = false
+}
@@ -0,0 +1,315 @@
+package pipelines
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newIntCodec() Codec[int] {
+	return NewCodec[int](
+		EncoderFunc[int](func(i int) ([]byte, error) { return []byte(strconv.Itoa(i)), nil }),
+		DecoderFunc[int](func(b []byte) (int, error) { return strconv.Atoi(string(b)) }),
+	)
+}
+
+func TestSpillFile(t *testing.T) {
+	dir := t.TempDir()
+	f := newSpillFile(SpillConfig[int]{Dir: dir, Codec: newIntCodec(), MaxBytes: 10})
+
+	// every record of a single digit takes 2 bytes with its length.
+	for i := 1; i <= 5; i++ {
+		if err := f.write(i); err != nil {
+			t.Fatalf("unexpected error writing %d: %v", i, err)
+		}
+	}
+	if err := f.write(6); !errors.Is(err, errSpillFull) || !f.full {
+		t.Errorf("expected errSpillFull, got %v", err)
+	}
+
+	var read []int
+	for !f.empty() {
+		token, lost, err := f.read()
+		if err != nil || lost != 0 {
+			t.Fatalf("unexpected error reading: %v", err)
+		}
+		read = append(read, token)
+	}
+	if !reflect.DeepEqual(read, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("expected tokens to be read in order, got %v", read)
+	}
+	if f.full {
+		t.Error("expected the budget to be restored after draining")
+	}
+	if info, _ := f.file.Stat(); info.Size() != 0 {
+		t.Errorf("expected the drained file to be truncated, got %d bytes", info.Size())
+	}
+
+	name := f.file.Name()
+	f.close()
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Error("expected the spill file to be removed")
+	}
+}
+
+func TestSpillFile_DecodeError(t *testing.T) {
+	f := newSpillFile(SpillConfig[int]{Dir: t.TempDir(), Codec: newIntCodec(), MaxBytes: 100})
+	defer f.close()
+	f.write(1)
+	f.file.WriteAt([]byte("x"), 1)
+	f.write(2)
+
+	if _, lost, err := f.read(); err == nil || lost != 1 {
+		t.Errorf("expected the corrupted token to be lost, got %d %v", lost, err)
+	}
+	if token, _, err := f.read(); err != nil || token != 2 {
+		t.Errorf("expected the next token to be read, got %d %v", token, err)
+	}
+}
+
+func TestSpillFile_CorruptedSize(t *testing.T) {
+	f := newSpillFile(SpillConfig[int]{Dir: t.TempDir(), Codec: newIntCodec(), MaxBytes: 100})
+	defer f.close()
+	f.write(1)
+	f.write(2)
+	// the length of the first record is overwritten with a huge size.
+	f.file.WriteAt(binary.AppendUvarint(nil, 1<<62), 0)
+
+	if _, lost, err := f.read(); err == nil || lost != 2 {
+		t.Errorf("expected all the tokens to be lost, got %d %v", lost, err)
+	}
+	if !f.empty() {
+		t.Error("expected the corrupted file to be discarded")
+	}
+}
+
+func TestSpillFile_Compact(t *testing.T) {
+	f := newSpillFile(SpillConfig[int]{Dir: t.TempDir(), Codec: newIntCodec(), MaxBytes: 10})
+	defer f.close()
+	for i := 1; i <= 5; i++ {
+		f.write(i)
+	}
+	if err := f.write(6); !errors.Is(err, errSpillFull) {
+		t.Fatalf("expected errSpillFull, got %v", err)
+	}
+
+	// once half of the file is read, its space is reused without waiting for the file to be drained.
+	for i := 1; i <= 3; i++ {
+		if token, _, err := f.read(); err != nil || token != i {
+			t.Fatalf("expected %d, got %d %v", i, token, err)
+		}
+	}
+	if f.full {
+		t.Error("expected the budget to be restored once the file can be compacted")
+	}
+	if err := f.write(6); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info, _ := f.file.Stat(); info.Size() != 6 {
+		t.Errorf("expected the compacted file to have 3 tokens, got %d bytes", info.Size())
+	}
+
+	var read []int
+	for !f.empty() {
+		token, _, _ := f.read()
+		read = append(read, token)
+	}
+	if !reflect.DeepEqual(read, []int{4, 5, 6}) {
+		t.Errorf("expected tokens to be read in order, got %v", read)
+	}
+}
+
+func TestSpillFile_TruncateError(t *testing.T) {
+	f := newSpillFile(SpillConfig[int]{Dir: t.TempDir(), Codec: newIntCodec(), MaxBytes: 10})
+	f.write(1)
+	// the file is reopened read only so that it can't be truncated.
+	name := f.file.Name()
+	f.file.Close()
+	f.file, _ = os.Open(name)
+
+	// the last token is read before truncating, so it is not lost.
+	if token, lost, err := f.read(); err != nil || lost != 0 || token != 1 {
+		t.Errorf("expected the token to be read, got %d %d %v", token, lost, err)
+	}
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Error("expected the spill file to be removed")
+	}
+	if err := f.write(2); err != nil {
+		t.Errorf("expected a new spill file to be created, got %v", err)
+	}
+	f.close()
+}
+
+func TestPipeline_InputQueue_Spill(t *testing.T) {
+	var order []int
+	blocked, release := make(chan struct{}), make(chan struct{})
+	builder := &Builder[int]{}
+	work := builder.NewStep(StepBasicConfig[int]{
+		Label: "work",
+		Process: func(i int) int {
+			if i == 0 {
+				close(blocked)
+				<-release
+			}
+			order = append(order, i)
+			return i
+		},
+		StepOptions: StepOptions[int]{
+			InputQueue: &InputQueueConfig[int]{
+				Spill: &SpillConfig[int]{Dir: t.TempDir(), Codec: newIntCodec(), MaxBytes: 1 << 20},
+			},
+		},
+	})
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(int) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, work, sink)
+	p.Init()
+	p.Run(context.Background())
+
+	p.FeedOne(0)
+	<-blocked
+	inputs := make([]int, 30)
+	for i := range inputs {
+		inputs[i] = i + 1
+	}
+	// feeding doesn't block since the tokens beyond the capacity are spilled.
+	p.FeedMany(inputs)
+	waitQueueLength(t, p, 10)
+	deadline := time.Now().Add(time.Second)
+	for p.Describe().Steps[0].Spilled != 20 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 20 spilled tokens, got %d", p.Describe().Steps[0].Spilled)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	close(release)
+	p.WaitTillDone()
+	p.Terminate()
+
+	if !reflect.DeepEqual(order, append([]int{0}, inputs...)) {
+		t.Errorf("expected tokens to be processed in order, got %v", order)
+	}
+}
+
+func TestPipeline_InputQueue_SpillTerminate(t *testing.T) {
+	var order []int
+	blocked, release := make(chan struct{}), make(chan struct{})
+	builder := &Builder[int]{}
+	work := builder.NewStep(StepBasicConfig[int]{
+		Label: "work",
+		Process: func(i int) int {
+			if i == 0 {
+				close(blocked)
+				<-release
+			}
+			order = append(order, i)
+			return i
+		},
+		StepOptions: StepOptions[int]{
+			InputQueue: &InputQueueConfig[int]{
+				Spill: &SpillConfig[int]{Dir: t.TempDir(), Codec: newIntCodec(), MaxBytes: 1 << 20},
+			},
+		},
+	})
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(int) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, work, sink)
+	p.Init()
+	p.Run(context.Background())
+
+	p.FeedOne(0)
+	<-blocked
+	inputs := make([]int, 30)
+	for i := range inputs {
+		inputs[i] = i + 1
+	}
+	p.FeedMany(inputs)
+	waitQueueLength(t, p, 10)
+
+	terminated := make(chan error)
+	go func() { terminated <- p.Terminate() }()
+	for p.State() != StateDraining {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	<-terminated
+
+	// the spilled tokens are dropped, while the queued tokens and the token left for the stopped sink are discarded
+	// with the channels.
+	if dropped := p.Describe().Steps[0].Dropped; dropped != 20 {
+		t.Errorf("expected the 20 spilled tokens to be dropped, got %d", dropped)
+	}
+	if p.TokensCount() != 11 {
+		t.Errorf("expected the dropped tokens not to be counted, got tokens count %d", p.TokensCount())
+	}
+}
+
+func TestPipeline_InputQueue_SpillBudget(t *testing.T) {
+	var order []int
+	blocked, release := make(chan struct{}), make(chan struct{})
+	builder := &Builder[int]{}
+	work := builder.NewStep(StepBasicConfig[int]{
+		Label: "work",
+		Process: func(i int) int {
+			if i == 0 {
+				close(blocked)
+				<-release
+			}
+			order = append(order, i)
+			return i
+		},
+		StepOptions: StepOptions[int]{
+			InputQueue: &InputQueueConfig[int]{
+				Overflow: OverflowDropNewest,
+				Spill:    &SpillConfig[int]{Dir: t.TempDir(), Codec: newIntCodec(), MaxBytes: 6},
+			},
+		},
+	})
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(int) {}})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, work, sink)
+	p.Init()
+	p.Run(context.Background())
+
+	p.FeedOne(0)
+	<-blocked
+	// 10 tokens are queued, the next 3 are spilled within the budget of 6 bytes, and the rest are dropped.
+	p.FeedMany([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15})
+
+	close(release)
+	p.WaitTillDone()
+	description := p.Describe()
+	p.Terminate()
+
+	if !reflect.DeepEqual(order, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}) {
+		t.Errorf("unexpected processed tokens: %v", order)
+	}
+	if description.Steps[0].Dropped != 3 {
+		t.Errorf("expected 3 dropped tokens, got %d", description.Steps[0].Dropped)
+	}
+}
+
+func TestSpillConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		spill *SpillConfig[int]
+	}{
+		{"MissingCodec", &SpillConfig[int]{MaxBytes: 10}},
+		{"MissingBudget", &SpillConfig[int]{Codec: newIntCodec()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			builder := &Builder[int]{}
+			builder.NewStep(StepBasicConfig[int]{Process: func(i int) int { return i }, StepOptions: StepOptions[int]{InputQueue: &InputQueueConfig[int]{Spill: tt.spill}}})
+		})
+	}
+}
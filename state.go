@@ -0,0 +1,159 @@
+package pipelines
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PipelineState is the lifecycle state of the pipeline.
+type PipelineState int32
+
+const (
+	// StateCreated is the state of the pipeline after being created by the builder.
+	StateCreated PipelineState = iota
+
+	// StateInitialized is the state of the pipeline after Init is called.
+	StateInitialized
+
+	// StateRunning is the state of the pipeline while the steps are running.
+	StateRunning
+
+	// StateDraining is the state of the pipeline while it is being terminated.
+	StateDraining
+
+	// StateTerminated is the state of the pipeline after Terminate is done.
+	StateTerminated
+
+	// StatePaused is the state of the pipeline while the replicas are stopped by Pause.
+	StatePaused
+)
+
+func (s PipelineState) String() string {
+	switch s {
+	case StateCreated:
+		return "created"
+	case StateInitialized:
+		return "initialized"
+	case StateRunning:
+		return "running"
+	case StateDraining:
+		return "draining"
+	case StateTerminated:
+		return "terminated"
+	case StatePaused:
+		return "paused"
+	default:
+		return fmt.Sprintf("unknown(%d)", int32(s))
+	}
+}
+
+// MarshalText encodes the state as its name.
+func (s PipelineState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// ErrInvalidState is matched by the errors returned when a method is called in a state that doesn't allow it.
+var ErrInvalidState = errors.New("invalid pipeline state")
+
+// StateError is returned when a method of the pipeline is called in a state that doesn't allow it.
+type StateError struct {
+
+	// Op is the name of the operation which is not allowed.
+	Op string
+
+	// State is the state of the pipeline when the operation was called.
+	State PipelineState
+}
+
+func (e *StateError) Error() string {
+	return fmt.Sprintf("cannot %s the pipeline while it is %s", e.Op, e.State)
+}
+
+// Is makes the state errors match ErrInvalidState.
+func (e *StateError) Is(target error) bool {
+	return target == ErrInvalidState
+}
+
+// recentErrorsLimit is the max number of errors retained by the pipeline.
+const recentErrorsLimit = 32
+
+// PipelineError is an error reported by the pipeline or one of its steps while running.
+type PipelineError struct {
+
+	// Time is the time the error was reported.
+	Time time.Time
+
+	// Step is the label of the step reporting the error. It is empty for errors reported by the pipeline itself.
+	Step string
+
+	// Err is the reported error.
+	Err error
+}
+
+func (e PipelineError) Error() string {
+	if e.Step == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("step %s: %v", e.Step, e.Err)
+}
+
+func (e PipelineError) Unwrap() error {
+	return e.Err
+}
+
+func (p *pipeline[I]) State() PipelineState {
+	p.stateMutex.Lock()
+	defer p.stateMutex.Unlock()
+	return p.state
+}
+
+// setState changes the current state of the pipeline.
+func (p *pipeline[I]) setState(state PipelineState) {
+	p.stateMutex.Lock()
+	defer p.stateMutex.Unlock()
+	p.state = state
+}
+
+func (p *pipeline[I]) RecentErrors() []PipelineError {
+	p.errorsMutex.Lock()
+	defer p.errorsMutex.Unlock()
+	errs := make([]PipelineError, len(p.recentErrors))
+	copy(errs, p.recentErrors)
+	return errs
+}
+
+// reportError records the error and passes it to the error handler if it is set.
+func (p *pipeline[I]) reportError(step string, err error) {
+	pipelineErr := PipelineError{Time: time.Now(), Step: step, Err: err}
+
+	p.errorsMutex.Lock()
+	if len(p.recentErrors) == recentErrorsLimit {
+		p.recentErrors = p.recentErrors[1:]
+	}
+	p.recentErrors = append(p.recentErrors, pipelineErr)
+	if p.collectedErrors != nil {
+		*p.collectedErrors = append(*p.collectedErrors, pipelineErr)
+	}
+	p.errorsMutex.Unlock()
+
+	if p.errorHandler != nil {
+		p.errorHandler(pipelineErr)
+	}
+}
+
+// errorReporting is implemented by the steps which report their errors to the pipeline.
+type errorReporting interface {
+	setErrorReporter(func(error))
+}
+
+func (s *stepBase[I]) setErrorReporter(reporter func(error)) {
+	s.errorReporter = reporter
+}
+
+// reportError passes the error of the step to the pipeline if the step is connected to one.
+func (s *stepBase[I]) reportError(err error) {
+	if s.errorReporter != nil {
+		s.errorReporter(err)
+	}
+}
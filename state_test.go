@@ -0,0 +1,133 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPipelineState_String(t *testing.T) {
+	states := map[PipelineState]string{
+		StateCreated:      "created",
+		StateInitialized:  "initialized",
+		StateRunning:      "running",
+		StatePaused:       "paused",
+		StateDraining:     "draining",
+		StateTerminated:   "terminated",
+		PipelineState(99): "unknown(99)",
+	}
+	for state, expected := range states {
+		if state.String() != expected {
+			t.Errorf("expected %s, got %s", expected, state.String())
+		}
+	}
+}
+
+func TestPipeline_StateTransitions(t *testing.T) {
+	p := &pipeline[int]{
+		steps:              []IStep[int]{&mockStep[int]{replicas: 1}, &mockStep[int]{replicas: 1, finalStep: true}},
+		defaultChannelSize: 10,
+	}
+	if p.State() != StateCreated {
+		t.Errorf("expected state to be created, got %s", p.State())
+	}
+	p.Init()
+	if p.State() != StateInitialized {
+		t.Errorf("expected state to be initialized, got %s", p.State())
+	}
+	p.Run(context.Background())
+	if p.State() != StateRunning {
+		t.Errorf("expected state to be running, got %s", p.State())
+	}
+	p.Terminate()
+	if p.State() != StateTerminated {
+		t.Errorf("expected state to be terminated, got %s", p.State())
+	}
+}
+
+func TestPipeline_ReportError(t *testing.T) {
+	var handled []PipelineError
+	p := &pipeline[int]{
+		errorHandler: func(err PipelineError) { handled = append(handled, err) },
+	}
+
+	cause := errors.New("failure")
+	for range recentErrorsLimit + 5 {
+		p.reportError("step", cause)
+	}
+
+	errs := p.RecentErrors()
+	if len(errs) != recentErrorsLimit {
+		t.Errorf("expected %d recent errors, got %d", recentErrorsLimit, len(errs))
+	}
+	if len(handled) != recentErrorsLimit+5 {
+		t.Errorf("expected handler to be called %d times, got %d", recentErrorsLimit+5, len(handled))
+	}
+	if !errors.Is(errs[0], cause) {
+		t.Error("expected reported error to wrap the cause")
+	}
+	if errs[0].Error() != "step step: failure" {
+		t.Errorf("unexpected error message: %s", errs[0].Error())
+	}
+}
+
+func TestPipeline_FeedAfterTerminationReportsError(t *testing.T) {
+	p := &pipeline[int]{
+		steps:              []IStep[int]{&mockStep[int]{replicas: 1}, &mockStep[int]{replicas: 1, finalStep: true}},
+		defaultChannelSize: 10,
+	}
+	p.Init()
+	p.Run(context.Background())
+	p.Terminate()
+
+	if err := p.FeedOne(1); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState feeding a terminated pipeline, got %v", err)
+	}
+	if len(p.RecentErrors()) != 1 {
+		t.Errorf("expected feeding after termination to report an error, got %d errors", len(p.RecentErrors()))
+	}
+}
+
+func TestPipeline_InvalidTransitions(t *testing.T) {
+	p := &pipeline[int]{
+		steps:              []IStep[int]{&mockStep[int]{replicas: 1}, &mockStep[int]{replicas: 1, finalStep: true}},
+		defaultChannelSize: 10,
+	}
+
+	var stateErr *StateError
+	if err := p.Run(context.Background()); !errors.As(err, &stateErr) || stateErr.Op != "run" || stateErr.State != StateCreated {
+		t.Errorf("expected state error running before init, got %v", err)
+	}
+	if err := p.FeedOne(1); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState feeding before init, got %v", err)
+	}
+	if err := p.FeedMany([]int{1, 2}); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState feeding before init, got %v", err)
+	}
+	if err := p.WaitTillDone(); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState waiting before init, got %v", err)
+	}
+	if err := p.Terminate(); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState terminating before run, got %v", err)
+	}
+	if len(p.RecentErrors()) != 0 {
+		t.Errorf("expected no reported errors before init, got %d", len(p.RecentErrors()))
+	}
+
+	p.Init()
+	if err := p.WaitTillDone(); !errors.Is(err, ErrTokensCountNotTracked) {
+		t.Errorf("expected ErrTokensCountNotTracked, got %v", err)
+	}
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := p.Run(context.Background()); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState running twice, got %v", err)
+	}
+	if err := p.Terminate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := p.Terminate(); !errors.As(err, &stateErr) || stateErr.State != StateTerminated {
+		t.Errorf("expected state error terminating twice, got %v", err)
+	}
+}
@@ -1,5 +1,17 @@
 package pipelines
 
+import "time"
+
+// StepOptions is embedded in the configurations of all steps.
+type StepOptions[I any] struct {
+
+	// Autoscale is the policy used to scale the replicas of the step while running. It is optional.
+	Autoscale *AutoscalePolicy
+
+	// InputQueue replaces the input channel of the step with a queue supporting priorities and overflow policies. It is optional.
+	InputQueue *InputQueueConfig[I]
+}
+
 // stepBase is a base struct for all steps
 type stepBase[I any] struct {
 
@@ -23,6 +35,21 @@ type stepBase[I any] struct {
 
 	// incrementTokensCount is a function that increments the number of tokens in the pipeline.
 	incrementTokensCount func()
+
+	// activity tracks the process calls of the replicas. It is nil unless the watchdog is enabled.
+	activity *stepActivity
+
+	// autoscale is the policy used to scale the replicas of the step. It is nil if the step is not autoscaled.
+	autoscale *AutoscalePolicy
+
+	// inputQueue is the configuration of the input queue of the step. It is nil if the step receives from a plain channel.
+	inputQueue *InputQueueConfig[I]
+
+	// lineages tracks the tokens derived from the fed tokens. It is nil if the pipeline doesn't track lineages.
+	lineages *lineageRegistry
+
+	// errorReporter reports the errors of the step to the pipeline. It is nil if the step is not connected to a pipeline.
+	errorReporter func(error)
 }
 
 func newBaseStep[I any](label string, replicas uint16, inputChannelSize uint16) stepBase[I] {
@@ -36,6 +63,12 @@ func newBaseStep[I any](label string, replicas uint16, inputChannelSize uint16)
 	return step
 }
 
+// setOptions validates and sets the options shared by all steps.
+func (s *stepBase[I]) setOptions(options StepOptions[I]) {
+	s.setAutoscalePolicy(options.Autoscale)
+	s.setInputQueue(options.InputQueue)
+}
+
 func (s *stepBase[I]) GetLabel() string {
 	return s.label
 }
@@ -75,3 +108,42 @@ func (s *stepBase[I]) SetDecrementTokensCountHandler(handler func()) {
 func (s *stepBase[I]) SetIncrementTokensCountHandler(handler func()) {
 	s.incrementTokensCount = handler
 }
+
+// recordTiming records the time taken by the step to process the token if it is an envelope.
+func (s *stepBase[I]) recordTiming(token I, start time.Time) {
+	if h := headerOf(token); h != nil {
+		h.addTiming(s.label, start)
+	}
+}
+
+// inherit passes the metadata of the parent token to the child token if both are envelopes and the child is not stamped yet.
+func (s *stepBase[I]) inherit(parent, child I) {
+	parentHeader := headerOf(parent)
+	childHeader := headerOf(child)
+	if parentHeader != nil && childHeader != nil {
+		childHeader.adopt(parentHeader)
+	}
+}
+
+func (s *stepBase[I]) trackActivity() *stepActivity {
+	if s.activity == nil {
+		s.activity = newStepActivity()
+	}
+	return s.activity
+}
+
+// beginProcess marks the start of a process call if the activity is tracked.
+func (s *stepBase[I]) beginProcess() uint64 {
+	if s.activity == nil {
+		return 0
+	}
+	return s.activity.begin()
+}
+
+// endProcess marks the end of a process call if the activity is tracked.
+func (s *stepBase[I]) endProcess(call uint64) {
+	if s.activity == nil {
+		return
+	}
+	s.activity.end(call)
+}
@@ -3,6 +3,7 @@ package pipelines
 import (
 	"context"
 	"sync"
+	"time"
 )
 
 // StepBasicProcess is a function that processes a single input data and returns a single output data.
@@ -21,6 +22,9 @@ type StepBasicConfig[I any] struct {
 
 	// Process is a function that will be applied to the incoming data
 	Process StepBasicProcess[I]
+
+	// StepOptions holds the options shared by all steps.
+	StepOptions[I]
 }
 
 type stepBasic[I any] struct {
@@ -34,10 +38,12 @@ func newStepBasic[I any](config StepBasicConfig[I]) IStep[I] {
 	if config.Process == nil {
 		panic("process is required")
 	}
-	return &stepBasic[I]{
+	step := &stepBasic[I]{
 		stepBase: newBaseStep[I](config.Label, config.Replicas, config.InputChannelSize),
 		process:  config.Process,
 	}
+	step.setOptions(config.StepOptions)
+	return step
 }
 
 // run is a method that runs the step process and will be executed in a separate goroutine.
@@ -51,7 +57,12 @@ func (s *stepBasic[I]) Run(ctx context.Context, wg *sync.WaitGroup) {
 			if !ok {
 				return
 			}
+			start := time.Now()
+			call := s.beginProcess()
 			o := s.process(i)
+			s.endProcess(call)
+			s.replace(i, o)
+			s.recordTiming(o, start)
 			s.output <- o
 		}
 	}
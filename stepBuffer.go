@@ -2,7 +2,9 @@ package pipelines
 
 import (
 	"context"
+	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -38,7 +40,9 @@ type StepBufferConfig[I any] struct {
 	// If PassThrough is set to false, the buffer will retain all the elements in the buffer.
 	PassThrough bool
 
-	// The process which is called when the input is received and added to the buffer.
+	// The process which is called when the input is received and added to the buffer. It is not called again when the
+	// buffer is flushed at the end of the input by RunAll, so the tokens retained by a buffer having only this process
+	// are dropped then.
 	InputTriggeredProcess StepBufferProcess[I]
 
 	// The process which is called periodically based on the interval.
@@ -46,6 +50,12 @@ type StepBufferConfig[I any] struct {
 
 	// TimeTriggeredProcessInterval is the interval at which the TimeTriggeredProcess is called.
 	TimeTriggeredProcessInterval time.Duration
+
+	// StepOptions holds the options shared by all steps.
+	StepOptions[I]
+
+	// Checkpoint enables saving the buffer to a storage and restoring it when the pipeline is initialized. It is optional.
+	Checkpoint *CheckpointConfig[I]
 }
 
 type stepBuffer[I any] struct {
@@ -55,9 +65,17 @@ type stepBuffer[I any] struct {
 	bufferMutex sync.Mutex
 	passThrough bool
 
+	// buffered is the length of the buffer. It is read without locking the buffer mutex, which is held while sending to
+	// the output.
+	buffered atomic.Int64
+
 	inputTriggeredProcess        StepBufferProcess[I]
 	timeTriggeredProcess         StepBufferProcess[I]
 	timeTriggeredProcessInterval time.Duration
+
+	// checkpoint is the configuration of checkpointing the buffer. It is nil if the buffer is not checkpointed.
+	checkpoint    *CheckpointConfig[I]
+	checkpointKey string
 }
 
 func newStepBuffer[I any](config StepBufferConfig[I]) IStep[I] {
@@ -71,7 +89,7 @@ func newStepBuffer[I any](config StepBufferConfig[I]) IStep[I] {
 		panic("buffer size must be greater than or equal to 0")
 	}
 
-	return &stepBuffer[I]{
+	step := &stepBuffer[I]{
 		stepBase:                     newBaseStep[I](config.Label, config.Replicas, config.InputChannelSize),
 		bufferSize:                   config.BufferSize,
 		passThrough:                  config.PassThrough,
@@ -80,15 +98,23 @@ func newStepBuffer[I any](config StepBufferConfig[I]) IStep[I] {
 		timeTriggeredProcess:         config.TimeTriggeredProcess,
 		timeTriggeredProcessInterval: config.TimeTriggeredProcessInterval,
 	}
+	step.setOptions(config.StepOptions)
+	if config.Checkpoint != nil {
+		step.checkpointKey = validateCheckpointConfig(config.Checkpoint, config.Label)
+		checkpoint := *config.Checkpoint
+		step.checkpoint = &checkpoint
+	}
+	return step
 }
 
 func (s *stepBuffer[I]) Run(ctx context.Context, wg *sync.WaitGroup) {
-	if s.timeTriggeredProcessInterval == 0 {
+	interval := s.timeTriggeredProcessInterval
+	if interval == 0 {
 		// 1000 hours is to cover the case where the time is not set. The buffer in this case will be input triggered.
 		// this is needed to keep the thread alive as well inc case there is a long delay in the input.
-		s.timeTriggeredProcessInterval = 1000 * time.Hour
+		interval = 1000 * time.Hour
 	}
-	ticker := time.NewTicker(s.timeTriggeredProcessInterval)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	defer wg.Done()
 	for {
@@ -109,15 +135,19 @@ func (s *stepBuffer[I]) Run(ctx context.Context, wg *sync.WaitGroup) {
 func (s *stepBuffer[I]) addToBuffer(i I) bool {
 	overwriteOccurred := false
 	if len(s.buffer) == s.bufferSize {
-		s.buffer = s.buffer[1:]
+		// the overwritten token leaves the pipeline.
+		s.release(s.buffer[0], outcomeDropped)
+		s.setBuffer(s.buffer[1:])
 		overwriteOccurred = true
 	}
-	s.buffer = append(s.buffer, i)
+	s.setBuffer(append(s.buffer, i))
 	return overwriteOccurred
 }
 
 func (s *stepBuffer[I]) handleInputTriggeredProcess(i I) {
 
+	start := time.Now()
+
 	// All the following has to be done in during the same mutex lock.
 	s.bufferMutex.Lock()
 	defer s.bufferMutex.Unlock()
@@ -133,7 +163,13 @@ func (s *stepBuffer[I]) handleInputTriggeredProcess(i I) {
 		if !overwriteOccurred {
 			s.incrementTokensCount()
 		}
+		// the stored token and the passed token are counted separately in the lineage.
+		s.hold(i)
+		s.recordTiming(i, start)
 		s.output <- i
+	} else if overwriteOccurred {
+		// the overwritten token was counted while it was retained.
+		s.decrementTokensCount()
 	}
 
 	// Checking if the input triggered process is set.
@@ -142,21 +178,27 @@ func (s *stepBuffer[I]) handleInputTriggeredProcess(i I) {
 	}
 
 	// Processing the buffer after adding the element.
+	call := s.beginProcess()
 	processOutput, flags := s.inputTriggeredProcess(s.buffer)
+	s.endProcess(call)
 
 	if flags.SendProcessOuput {
 		// Since this is a new result, we need to increment the tokens count.
 		s.incrementTokensCount()
+		// The result is aggregated from multiple tokens, so it is stamped as a new one.
+		s.aggregate(s.buffer, processOutput)
+		stampEnvelope(processOutput)
+		s.recordTiming(processOutput, start)
 		s.output <- processOutput
 	}
 
 	// Check if the buffer should be flushed or not.
 	if flags.FlushBuffer {
-		length := len(s.buffer)
-		for range length {
+		for _, token := range s.buffer {
+			s.release(token, outcomeDerived)
 			s.decrementTokensCount()
 		}
-		s.buffer = s.buffer[:0]
+		s.setBuffer(s.buffer[:0])
 	}
 }
 
@@ -166,23 +208,155 @@ func (s *stepBuffer[I]) handleTimeTriggeredProcess() {
 		return
 	}
 
+	start := time.Now()
+
 	s.bufferMutex.Lock()
 	defer s.bufferMutex.Unlock()
 
+	call := s.beginProcess()
 	processOutput, flags := s.timeTriggeredProcess(s.buffer)
+	s.endProcess(call)
 
 	// Check if the process has a result or not.
 	if flags.SendProcessOuput {
 		s.incrementTokensCount()
+		s.aggregate(s.buffer, processOutput)
+		stampEnvelope(processOutput)
+		s.recordTiming(processOutput, start)
 		s.output <- processOutput
 	}
 
 	// Check if the buffer should be flushed or not.
 	if flags.FlushBuffer {
-		length := len(s.buffer)
-		for range length {
+		for _, token := range s.buffer {
+			s.release(token, outcomeDerived)
 			s.decrementTokensCount()
 		}
-		s.buffer = s.buffer[:0]
+		s.setBuffer(s.buffer[:0])
+	}
+}
+
+// setBuffer replaces the buffer while the buffer mutex is held.
+func (s *stepBuffer[I]) setBuffer(buffer []I) {
+	s.buffer = buffer
+	s.buffered.Store(int64(len(buffer)))
+}
+
+// describe returns the settings and the current state of the buffer.
+// It doesn't lock the buffer, so that it doesn't wait for the following steps while the buffer is sending to them.
+func (s *stepBuffer[I]) describe() *BufferDescription {
+	return &BufferDescription{
+		BufferSize:                   s.bufferSize,
+		Buffered:                     int(s.buffered.Load()),
+		PassThrough:                  s.passThrough,
+		InputTriggered:               s.inputTriggeredProcess != nil,
+		TimeTriggered:                s.timeTriggeredProcess != nil,
+		TimeTriggeredProcessInterval: s.timeTriggeredProcessInterval,
+	}
+}
+
+func (s *stepBuffer[I]) retainedTokens() int {
+	return int(s.buffered.Load())
+}
+
+func (s *stepBuffer[I]) locked(f func(int)) {
+	s.bufferMutex.Lock()
+	defer s.bufferMutex.Unlock()
+	f(len(s.buffer))
+}
+
+func (s *stepBuffer[I]) clearBuffer() {
+	s.bufferMutex.Lock()
+	defer s.bufferMutex.Unlock()
+	for _, token := range s.buffer {
+		s.release(token, outcomeDropped)
+	}
+	s.setBuffer(s.buffer[:0])
+}
+
+func (s *stepBuffer[I]) flush() {
+	start := time.Now()
+
+	s.bufferMutex.Lock()
+	defer s.bufferMutex.Unlock()
+
+	if len(s.buffer) == 0 {
+		return
+	}
+
+	// the input triggered process already ran over the retained tokens when they were added, so only the time
+	// triggered process runs once more. Without it, or if it sends nothing, the retained tokens are dropped.
+	outcome := outcomeDropped
+	if s.timeTriggeredProcess != nil {
+		call := s.beginProcess()
+		processOutput, flags := s.timeTriggeredProcess(s.buffer)
+		s.endProcess(call)
+
+		if flags.SendProcessOuput {
+			s.incrementTokensCount()
+			s.aggregate(s.buffer, processOutput)
+			stampEnvelope(processOutput)
+			s.recordTiming(processOutput, start)
+			s.output <- processOutput
+			outcome = outcomeDerived
+		}
+	}
+
+	// the remaining tokens are released whether the process flushes the buffer or not.
+	for _, token := range s.buffer {
+		s.release(token, outcome)
+		s.decrementTokensCount()
+	}
+	s.setBuffer(s.buffer[:0])
+}
+
+func (s *stepBuffer[I]) saveCheckpoint() error {
+	if s.checkpoint == nil {
+		return nil
+	}
+	s.bufferMutex.Lock()
+	data, err := encodeRecords[I](s.checkpoint.Codec, s.buffer)
+	s.bufferMutex.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.checkpoint.Storage.Save(s.checkpointKey, data)
+}
+
+func (s *stepBuffer[I]) restoreCheckpoint() (int, error) {
+	if s.checkpoint == nil {
+		return 0, nil
+	}
+	data, err := s.checkpoint.Storage.Load(s.checkpointKey)
+	if errors.Is(err, ErrCheckpointNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	tokens, err := decodeRecords[I](s.checkpoint.Codec, data)
+	if err != nil {
+		return 0, err
+	}
+	// keeping the most recent tokens in case the buffer size is reduced since the checkpoint was saved.
+	if len(tokens) > s.bufferSize {
+		tokens = tokens[len(tokens)-s.bufferSize:]
+	}
+	// the lineages of the restored tokens are not tracked since their roots belong to a previous run.
+	for _, token := range tokens {
+		if h := headerOf(token); h != nil {
+			h.setRoots(nil)
+		}
+	}
+	s.bufferMutex.Lock()
+	defer s.bufferMutex.Unlock()
+	s.setBuffer(append(s.buffer[:0], tokens...))
+	return len(tokens), nil
+}
+
+func (s *stepBuffer[I]) checkpointInterval() time.Duration {
+	if s.checkpoint == nil {
+		return 0
 	}
+	return s.checkpoint.Interval
 }
@@ -154,6 +154,54 @@ func TestStepBuffer_Run_InputTriggered_FlushBuffer(t *testing.T) {
 	}
 }
 
+func TestStepBuffer_Run_InputTriggered_Overwrite(t *testing.T) {
+	incrementTokens := &mockIncrementTokensHandler{}
+	decrementTokens := &mockDecrementTokensHandler{}
+
+	step := &stepBuffer[int]{
+		stepBase: stepBase[int]{
+			input:                make(chan int),
+			output:               make(chan int),
+			incrementTokensCount: incrementTokens.Handle,
+			decrementTokensCount: decrementTokens.Handle,
+		},
+		bufferSize:  2,
+		passThrough: false,
+		inputTriggeredProcess: func(buffer []int) (int, BufferFlags) {
+			return 0, BufferFlags{}
+		},
+	}
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go step.Run(ctx, &wg)
+
+	step.input <- 1
+	step.input <- 2
+	step.input <- 3
+	step.input <- 4
+
+	cancelCtx()
+	wg.Wait()
+	close(step.input)
+	close(step.output)
+
+	expectedBuffer := []int{3, 4}
+	if !equal(step.buffer, expectedBuffer) {
+		t.Errorf("expected %v, got %v", expectedBuffer, step.buffer)
+	}
+
+	if incrementTokens.called {
+		t.Errorf("expected incrementTokens to not be called")
+	}
+
+	// the two overwritten tokens leave the pipeline.
+	if decrementTokens.counter != -2 {
+		t.Errorf("expected decrementTokens to be called 2 times, got %d", decrementTokens.counter)
+	}
+}
+
 func TestStepBuffer_HandleTimeTriggeredProcess_FlushBuffer(t *testing.T) {
 
 	incrementTokens := &mockIncrementTokensHandler{}
@@ -430,6 +478,42 @@ func TestStepBuffer_NewStep_MissingBufferSize(t *testing.T) {
 	newStepBuffer(stepConfig)
 }
 
+func TestStepBuffer_Describe_WhileSending(t *testing.T) {
+	step := newStepBuffer(StepBufferConfig[int]{
+		BufferSize:  2,
+		PassThrough: true,
+		InputTriggeredProcess: func(buffer []int) (int, BufferFlags) {
+			return 0, BufferFlags{}
+		},
+	}).(*stepBuffer[int])
+	step.input = make(chan int)
+	step.output = make(chan int)
+	step.incrementTokensCount = func() {}
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go step.Run(ctx, &wg)
+
+	// the token is passed while the buffer is locked, and nothing receives it.
+	step.input <- 1
+
+	described := make(chan *BufferDescription)
+	go func() { described <- step.describe() }()
+	select {
+	case description := <-described:
+		if description.Buffered != 1 {
+			t.Errorf("expected 1 buffered token, got %d", description.Buffered)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected describe not to wait for the output")
+	}
+
+	<-step.output
+	cancelCtx()
+	wg.Wait()
+}
+
 func equal(a, b []int) bool {
 	if len(a) != len(b) {
 		return false
@@ -441,3 +525,32 @@ func equal(a, b []int) bool {
 	}
 	return true
 }
+
+func TestStepBuffer_Flush_InputTriggeredOnly(t *testing.T) {
+	registry := newLineageRegistry()
+	decrementTokens := &mockDecrementTokensHandler{}
+	step := newStepBuffer(StepBufferConfig[*Envelope[int]]{
+		BufferSize: 5,
+		InputTriggeredProcess: func(buffer []*Envelope[int]) (*Envelope[int], BufferFlags) {
+			return nil, BufferFlags{}
+		},
+	}).(*stepBuffer[*Envelope[int]])
+	step.setLineageRegistry(registry)
+	step.decrementTokensCount = decrementTokens.Handle
+
+	var outcomes []TokenOutcome
+	for _, e := range []*Envelope[int]{NewEnvelope(1), NewEnvelope(2)} {
+		e.stamp()
+		registry.track(&e.envelopeHeader, false, func(l *lineage, _ error) { outcomes = append(outcomes, l.outcome) })
+		step.addToBuffer(e)
+	}
+
+	// the input triggered process is not called again, so the retained tokens are dropped.
+	step.flush()
+	if len(outcomes) != 2 || outcomes[0].Dropped != 1 || outcomes[1].Dropped != 1 {
+		t.Errorf("expected the retained tokens to be dropped, got %+v", outcomes)
+	}
+	if decrementTokens.counter != -2 || step.retainedTokens() != 0 {
+		t.Errorf("expected the retained tokens to leave the pipeline, got %d retained", step.retainedTokens())
+	}
+}
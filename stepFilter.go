@@ -3,6 +3,7 @@ package pipelines
 import (
 	"context"
 	"sync"
+	"time"
 )
 
 // StepFilterPassCriteria is function that determines if the data should be passed or not.
@@ -22,6 +23,9 @@ type StepFilterConfig[I any] struct {
 
 	// PassCriteria is a function that determines if the data should be passed or not.
 	PassCriteria StepFilterPassCriteria[I]
+
+	// StepOptions holds the options shared by all steps.
+	StepOptions[I]
 }
 
 type stepFilter[I any] struct {
@@ -33,10 +37,12 @@ func newStepFilter[I any](config StepFilterConfig[I]) IStep[I] {
 	if config.PassCriteria == nil {
 		panic("process is required")
 	}
-	return &stepFilter[I]{
+	step := &stepFilter[I]{
 		stepBase:     newBaseStep[I](config.Label, config.Replicas, config.InputChannelSize),
 		passCriteria: config.PassCriteria,
 	}
+	step.setOptions(config.StepOptions)
+	return step
 }
 
 // run is a method that runs the step process and will be executed in a separate goroutine.
@@ -50,9 +56,15 @@ func (s *stepFilter[I]) Run(ctx context.Context, wg *sync.WaitGroup) {
 			if !ok {
 				return
 			}
-			if s.passCriteria(i) {
+			start := time.Now()
+			call := s.beginProcess()
+			pass := s.passCriteria(i)
+			s.endProcess(call)
+			if pass {
+				s.recordTiming(i, start)
 				s.output <- i
 			} else {
+				s.release(i, outcomeFiltered)
 				s.decrementTokensCount()
 			}
 		}
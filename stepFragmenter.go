@@ -3,6 +3,7 @@ package pipelines
 import (
 	"context"
 	"sync"
+	"time"
 )
 
 // StepFragmenterProcess is a function that converts a token in the pipeline into multiple tokens.
@@ -22,6 +23,9 @@ type StepFragmenterConfig[I any] struct {
 
 	// Process is the function that converts a token in the pipeline into multiple tokens.
 	Process StepFragmenterProcess[I]
+
+	// StepOptions holds the options shared by all steps.
+	StepOptions[I]
 }
 
 type stepFragmenter[I any] struct {
@@ -33,10 +37,12 @@ func newStepFragmenter[I any](config StepFragmenterConfig[I]) IStep[I] {
 	if config.Process == nil {
 		panic("process is required")
 	}
-	return &stepFragmenter[I]{
+	step := &stepFragmenter[I]{
 		stepBase: newBaseStep[I](config.Label, config.Replicas, config.InputChannelSize),
 		process:  config.Process,
 	}
+	step.setOptions(config.StepOptions)
+	return step
 }
 
 func (s *stepFragmenter[I]) Run(ctx context.Context, wg *sync.WaitGroup) {
@@ -49,13 +55,24 @@ func (s *stepFragmenter[I]) Run(ctx context.Context, wg *sync.WaitGroup) {
 			if !ok {
 				return
 			}
+			start := time.Now()
+			call := s.beginProcess()
 			outFragments := s.process(i)
+			s.endProcess(call)
+			s.recordTiming(i, start)
 			for _, fragment := range outFragments {
+				// fragments carry the metadata and the lineage of the token they are created from.
+				s.derive(i, fragment)
 				// adding fragmented tokens to the count.
 				s.incrementTokensCount()
 				s.output <- fragment
 			}
 			// whether the token is framented or filtered with error, it is discarded from the pipeline.
+			if len(outFragments) == 0 {
+				s.release(i, outcomeFiltered)
+			} else {
+				s.release(i, outcomeDerived)
+			}
 			s.decrementTokensCount()
 		}
 	}
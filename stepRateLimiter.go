@@ -0,0 +1,406 @@
+package pipelines
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimitAlgorithm is the algorithm used by the rate limiter step to enforce the rate.
+type RateLimitAlgorithm int
+
+const (
+	// TokenBucket passes the tokens immediately as long as the bucket has enough credit. The bucket is refilled at the rate
+	// up to the burst, so short bursts pass without delay after idle periods.
+	TokenBucket RateLimitAlgorithm = iota
+
+	// LeakyBucket delays the tokens so that they leave the step evenly spaced at the rate. The burst is the max number of
+	// tokens which can wait in the bucket.
+	LeakyBucket
+)
+
+// RateLimitExcess is the action taken by the rate limiter step on the tokens exceeding the rate.
+type RateLimitExcess int
+
+const (
+	// RateLimitBlock makes the excess tokens wait till they are allowed to pass, which blocks the previous steps.
+	RateLimitBlock RateLimitExcess = iota
+
+	// RateLimitDrop drops the excess tokens.
+	RateLimitDrop
+
+	// RateLimitDivert passes the excess tokens to the divert function instead of the following step.
+	RateLimitDivert
+)
+
+// StepRateLimiterConfig is a struct that defines the configuration for a rate limiter step.
+// The rate limiter step forwards the tokens to the following step at a limited rate shared by all its replicas.
+type StepRateLimiterConfig[I any] struct {
+
+	// Label is the name of the step.
+	Label string
+
+	// Replicas is the number of replicas (go routines) created to run the step.
+	Replicas uint16
+
+	// InputChannelSize is the buffer size for the input channel to the step
+	InputChannelSize uint16
+
+	// Rate is the number of tokens allowed to pass per second.
+	Rate float64
+
+	// Burst is the max number of tokens which can pass at once for the token bucket, and the max number of tokens which
+	// can wait in the leaky bucket when the excess tokens are dropped or diverted. It is set to 1 if it is not set.
+	Burst uint16
+
+	// Algorithm is the algorithm used to enforce the rate. It is TokenBucket if it is not set.
+	Algorithm RateLimitAlgorithm
+
+	// Excess is the action taken on the tokens exceeding the rate. It is RateLimitBlock if it is not set.
+	Excess RateLimitExcess
+
+	// Divert is called with the excess tokens if Excess is RateLimitDivert. The diverted tokens leave the pipeline.
+	Divert func(I)
+
+	// StepOptions holds the options shared by all steps.
+	StepOptions[I]
+}
+
+// rateLimiter keeps the state of the rate shared by all replicas of the step.
+type rateLimiter struct {
+	algorithm RateLimitAlgorithm
+	rate      float64
+	burst     float64
+
+	// interval is the time between two tokens at the rate.
+	interval time.Duration
+
+	// credit is the number of tokens which can pass immediately for the token bucket.
+	// It goes below zero for the tokens waiting to pass.
+	credit float64
+
+	// refilled is the time the credit was last refilled.
+	refilled time.Time
+
+	// next is the time the next token can leave the leaky bucket.
+	next time.Time
+
+	// mutex protects the state from race conditions.
+	mutex sync.Mutex
+}
+
+func newRateLimiter(algorithm RateLimitAlgorithm, rate float64, burst uint16) *rateLimiter {
+	return &rateLimiter{
+		algorithm: algorithm,
+		rate:      rate,
+		burst:     float64(burst),
+		interval:  time.Duration(float64(time.Second) / rate),
+		credit:    float64(burst),
+		refilled:  time.Now(),
+	}
+}
+
+// reserve returns the delay after which the token can pass.
+// If wait is false, no delay is reserved for the tokens exceeding the rate of the token bucket, and false is returned instead.
+func (l *rateLimiter) reserve(now time.Time, wait bool) (time.Duration, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.algorithm == LeakyBucket {
+		start := l.next
+		if start.Before(now) {
+			start = now
+		}
+		l.next = start.Add(l.interval)
+		return start.Sub(now), true
+	}
+
+	l.credit = min(l.burst, l.credit+now.Sub(l.refilled).Seconds()*l.rate)
+	l.refilled = now
+	if l.credit >= 1 {
+		l.credit--
+		return 0, true
+	}
+	if !wait {
+		return 0, false
+	}
+	l.credit--
+	return time.Duration(-l.credit / l.rate * float64(time.Second)), true
+}
+
+// delay returns the time left till the next token can leave the leaky bucket.
+func (l *rateLimiter) delay(now time.Time) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return max(0, l.next.Sub(now))
+}
+
+// waitingToken is a token waiting in the leaky bucket.
+type waitingToken[I any] struct {
+	token   I
+	arrived time.Time
+}
+
+type stepRateLimiter[I any] struct {
+	stepBase[I]
+	limiter *rateLimiter
+	excess  RateLimitExcess
+	divert  func(I)
+
+	// bucket holds the tokens waiting in the leaky bucket when the excess tokens are not blocked.
+	// The tokens are read from the input as soon as they arrive, so the tokens overflowing the bucket are the excess ones.
+	bucket     []waitingToken[I]
+	bucketSize int
+
+	// leak fires when the first waiting token can leave the bucket. It is nil if the tokens don't wait in the bucket.
+	leak *time.Timer
+
+	// interrupted holds the blocked tokens whose replica was stopped while they were waiting, like when the step is scaled
+	// down or paused. They are admitted again by the remaining replicas, so they don't skip the rate.
+	interrupted []I
+
+	// resume is signaled when there are interrupted tokens to be admitted again.
+	resume chan struct{}
+
+	// bucketMutex protects the bucket and the interrupted tokens from race conditions. It is never held while sending
+	// to the output.
+	bucketMutex sync.Mutex
+}
+
+func newStepRateLimiter[I any](config StepRateLimiterConfig[I]) IStep[I] {
+	if config.Rate <= 0 {
+		panic("rate must be greater than 0")
+	}
+	if config.Algorithm != TokenBucket && config.Algorithm != LeakyBucket {
+		panic("unknown rate limit algorithm")
+	}
+	if config.Excess < RateLimitBlock || config.Excess > RateLimitDivert {
+		panic("unknown rate limit excess action")
+	}
+	if config.Excess == RateLimitDivert && config.Divert == nil {
+		panic("divert is required when excess tokens are diverted")
+	}
+	burst := config.Burst
+	if burst == 0 {
+		burst = 1
+	}
+	step := &stepRateLimiter[I]{
+		stepBase: newBaseStep[I](config.Label, config.Replicas, config.InputChannelSize),
+		limiter:  newRateLimiter(config.Algorithm, config.Rate, burst),
+		excess:   config.Excess,
+		divert:   config.Divert,
+		resume:   make(chan struct{}, 1),
+	}
+	if config.Algorithm == LeakyBucket && config.Excess != RateLimitBlock {
+		// the blocked tokens wait in the input instead, which throttles the previous steps.
+		step.bucket = make([]waitingToken[I], 0, burst)
+		step.bucketSize = int(burst)
+		step.leak = time.NewTimer(0)
+		step.leak.Stop()
+	}
+	step.setOptions(config.StepOptions)
+	return step
+}
+
+func (s *stepRateLimiter[I]) Run(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	// all the replicas receive from the same timer, so each waiting token is leaked by one of them.
+	var leaked <-chan time.Time
+	if s.leak != nil {
+		leaked = s.leak.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-leaked:
+			if waiting, ok := s.leakNext(time.Now()); ok {
+				s.recordTiming(waiting.token, waiting.arrived)
+				s.output <- waiting.token
+			}
+		case <-s.resume:
+			if i, ok := s.takeInterrupted(); ok {
+				s.process(ctx, i)
+			}
+		case i, ok := <-s.input:
+			if !ok {
+				return
+			}
+			s.process(ctx, i)
+		}
+	}
+}
+
+// process passes, queues, or drops the token according to the rate.
+func (s *stepRateLimiter[I]) process(ctx context.Context, i I) {
+	start := time.Now()
+	call := s.beginProcess()
+	pass, queued := s.admit(ctx, i, start)
+	s.endProcess(call)
+	if queued {
+		return
+	}
+	if pass {
+		s.recordTiming(i, start)
+		s.output <- i
+		return
+	}
+	if s.excess == RateLimitDivert {
+		s.divert(i)
+	}
+	s.release(i, outcomeDropped)
+	s.decrementTokensCount()
+}
+
+// admit checks if the token can pass now, or if it is queued in the bucket to pass later.
+// The blocked tokens wait in line till they can pass.
+func (s *stepRateLimiter[I]) admit(ctx context.Context, i I, now time.Time) (pass bool, queued bool) {
+	if s.leak != nil {
+		return s.offer(i, now)
+	}
+	delay, pass := s.limiter.reserve(now, s.excess == RateLimitBlock)
+	if pass && !wait(ctx, delay) {
+		// the replica is stopped, so the token is kept to wait again in another replica instead of passing early.
+		s.interrupt(i)
+		return false, true
+	}
+	return pass, false
+}
+
+// interrupt keeps the token whose wait is interrupted, and signals the replicas to admit it again.
+func (s *stepRateLimiter[I]) interrupt(i I) {
+	s.bucketMutex.Lock()
+	s.interrupted = append(s.interrupted, i)
+	s.bucketMutex.Unlock()
+	s.signalResume()
+}
+
+// takeInterrupted removes the first interrupted token, and signals the replicas again if more tokens are left.
+func (s *stepRateLimiter[I]) takeInterrupted() (I, bool) {
+	s.bucketMutex.Lock()
+	defer s.bucketMutex.Unlock()
+	if len(s.interrupted) == 0 {
+		var zero I
+		return zero, false
+	}
+	i := s.interrupted[0]
+	s.interrupted = s.interrupted[1:]
+	if len(s.interrupted) > 0 {
+		s.signalResume()
+	}
+	return i, true
+}
+
+func (s *stepRateLimiter[I]) signalResume() {
+	select {
+	case s.resume <- struct{}{}:
+	default:
+	}
+}
+
+// offer passes the token immediately if the bucket is empty and the rate allows it, otherwise it queues the token in the
+// bucket if it is not full.
+func (s *stepRateLimiter[I]) offer(i I, now time.Time) (pass bool, queued bool) {
+	s.bucketMutex.Lock()
+	defer s.bucketMutex.Unlock()
+
+	if len(s.bucket) == 0 && s.limiter.delay(now) == 0 {
+		s.limiter.reserve(now, true)
+		return true, false
+	}
+	if len(s.bucket) == s.bucketSize {
+		return false, false
+	}
+	s.bucket = append(s.bucket, waitingToken[I]{token: i, arrived: now})
+	if len(s.bucket) == 1 {
+		s.leak.Reset(s.limiter.delay(now))
+	}
+	return false, true
+}
+
+// leakNext removes the first waiting token from the bucket if it is due, and sets the timer for the next one.
+func (s *stepRateLimiter[I]) leakNext(now time.Time) (waitingToken[I], bool) {
+	s.bucketMutex.Lock()
+	defer s.bucketMutex.Unlock()
+
+	if len(s.bucket) == 0 {
+		return waitingToken[I]{}, false
+	}
+	if delay := s.limiter.delay(now); delay > 0 {
+		s.leak.Reset(delay)
+		return waitingToken[I]{}, false
+	}
+	s.limiter.reserve(now, true)
+	waiting := s.bucket[0]
+	s.bucket[0] = waitingToken[I]{}
+	s.bucket = s.bucket[1:]
+	if len(s.bucket) > 0 {
+		s.leak.Reset(s.limiter.interval)
+	}
+	return waiting, true
+}
+
+func (s *stepRateLimiter[I]) retainedTokens() int {
+	s.bucketMutex.Lock()
+	defer s.bucketMutex.Unlock()
+	return len(s.bucket) + len(s.interrupted)
+}
+
+func (s *stepRateLimiter[I]) locked(f func(int)) {
+	s.bucketMutex.Lock()
+	defer s.bucketMutex.Unlock()
+	f(len(s.bucket) + len(s.interrupted))
+}
+
+func (s *stepRateLimiter[I]) clearBuffer() {
+	s.bucketMutex.Lock()
+	defer s.bucketMutex.Unlock()
+	if s.leak != nil {
+		s.leak.Stop()
+	}
+	for _, waiting := range s.bucket {
+		s.release(waiting.token, outcomeDropped)
+	}
+	s.bucket = s.bucket[:0]
+	for _, i := range s.interrupted {
+		s.release(i, outcomeDropped)
+	}
+	s.interrupted = nil
+}
+
+// flush leaks the waiting tokens at the rate till the bucket is empty. The interrupted tokens are admitted by the replicas.
+func (s *stepRateLimiter[I]) flush() {
+	for s.bucketed() > 0 {
+		waiting, ok := s.leakNext(time.Now())
+		if !ok {
+			time.Sleep(s.limiter.delay(time.Now()))
+			continue
+		}
+		s.recordTiming(waiting.token, waiting.arrived)
+		s.output <- waiting.token
+	}
+}
+
+// bucketed returns the number of tokens waiting in the bucket.
+func (s *stepRateLimiter[I]) bucketed() int {
+	s.bucketMutex.Lock()
+	defer s.bucketMutex.Unlock()
+	return len(s.bucket)
+}
+
+// wait blocks for the given delay or till the context is cancelled, and returns false if the context is cancelled first.
+func wait(ctx context.Context, delay time.Duration) bool {
+	if delay <= 0 {
+		return true
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,230 @@
+package pipelines
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_TokenBucket(t *testing.T) {
+	limiter := newRateLimiter(TokenBucket, 10, 3)
+	now := limiter.refilled
+
+	for i := 0; i < 3; i++ {
+		if delay, ok := limiter.reserve(now, false); !ok || delay != 0 {
+			t.Fatalf("expected token %d of the burst to pass immediately, got %v %v", i, delay, ok)
+		}
+	}
+	if _, ok := limiter.reserve(now, false); ok {
+		t.Error("expected token exceeding the burst to be rejected")
+	}
+	if delay, ok := limiter.reserve(now, true); !ok || delay != 100*time.Millisecond {
+		t.Errorf("expected waiting token to be delayed 100ms, got %v %v", delay, ok)
+	}
+
+	// the waiting token consumed the credit of the first 100ms.
+	now = now.Add(300 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if _, ok := limiter.reserve(now, false); !ok {
+			t.Fatalf("expected token %d to pass after refill", i)
+		}
+	}
+	if _, ok := limiter.reserve(now, false); ok {
+		t.Error("expected token exceeding the refilled credit to be rejected")
+	}
+}
+
+func TestRateLimiter_LeakyBucket(t *testing.T) {
+	limiter := newRateLimiter(LeakyBucket, 10, 2)
+	now := time.Now()
+
+	if delay, ok := limiter.reserve(now, true); !ok || delay != 0 {
+		t.Errorf("expected first token to pass immediately, got %v %v", delay, ok)
+	}
+	if delay := limiter.delay(now); delay != 100*time.Millisecond {
+		t.Errorf("expected next token to be due in 100ms, got %v", delay)
+	}
+	if delay, ok := limiter.reserve(now, true); !ok || delay != 100*time.Millisecond {
+		t.Errorf("expected second token to wait 100ms, got %v %v", delay, ok)
+	}
+	if delay, ok := limiter.reserve(now, true); !ok || delay != 200*time.Millisecond {
+		t.Errorf("expected third token to wait 200ms, got %v %v", delay, ok)
+	}
+
+	now = now.Add(time.Second)
+	if delay, ok := limiter.reserve(now, true); !ok || delay != 0 {
+		t.Errorf("expected token to pass immediately after the bucket is empty, got %v %v", delay, ok)
+	}
+}
+
+func runRateLimiterStep(config StepRateLimiterConfig[int], inputs []int) ([]int, *mockDecrementTokensHandler) {
+	step := newStepRateLimiter(config).(*stepRateLimiter[int])
+	decrementHandler := &mockDecrementTokensHandler{}
+	step.input = make(chan int, len(inputs))
+	step.output = make(chan int, len(inputs))
+	step.decrementTokensCount = decrementHandler.Handle
+
+	for _, i := range inputs {
+		step.input <- i
+	}
+	close(step.input)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	step.Run(context.Background(), &wg)
+	// the tokens left waiting in the bucket are leaked after the input is done.
+	step.flush()
+	close(step.output)
+
+	var results []int
+	for o := range step.output {
+		results = append(results, o)
+	}
+	return results, decrementHandler
+}
+
+func TestStepRateLimiter_Drop(t *testing.T) {
+	results, decrementHandler := runRateLimiterStep(StepRateLimiterConfig[int]{
+		Rate:   1,
+		Burst:  2,
+		Excess: RateLimitDrop,
+	}, []int{1, 2, 3, 4, 5})
+
+	if len(results) != 2 || results[0] != 1 || results[1] != 2 {
+		t.Errorf("expected the burst [1 2] to pass, got %v", results)
+	}
+	if decrementHandler.counter != -3 {
+		t.Errorf("expected 3 dropped tokens to be decremented, got %d", -decrementHandler.counter)
+	}
+}
+
+func TestStepRateLimiter_Divert(t *testing.T) {
+	var diverted []int
+	results, decrementHandler := runRateLimiterStep(StepRateLimiterConfig[int]{
+		Rate:      20,
+		Burst:     2,
+		Algorithm: LeakyBucket,
+		Excess:    RateLimitDivert,
+		Divert:    func(i int) { diverted = append(diverted, i) },
+	}, []int{1, 2, 3, 4, 5})
+
+	// the first token passes immediately and the next 2 wait in the bucket.
+	if len(results) != 3 || results[0] != 1 || results[1] != 2 || results[2] != 3 {
+		t.Errorf("expected [1 2 3] to pass, got %v", results)
+	}
+	if len(diverted) != 2 || diverted[0] != 4 || diverted[1] != 5 {
+		t.Errorf("expected [4 5] to be diverted, got %v", diverted)
+	}
+	if decrementHandler.counter != -2 {
+		t.Errorf("expected 2 diverted tokens to be decremented, got %d", -decrementHandler.counter)
+	}
+}
+
+func TestStepRateLimiter_LeakyBucket_Drop(t *testing.T) {
+	start := time.Now()
+	results, decrementHandler := runRateLimiterStep(StepRateLimiterConfig[int]{
+		Replicas:  1,
+		Rate:      20,
+		Burst:     4,
+		Algorithm: LeakyBucket,
+		Excess:    RateLimitDrop,
+	}, []int{1, 2, 3, 4, 5, 6, 7, 8})
+
+	// the bucket holds more tokens than the replicas, so the tokens overflowing it are dropped.
+	if len(results) != 5 {
+		t.Errorf("expected [1 2 3 4 5] to pass, got %v", results)
+	}
+	if decrementHandler.counter != -3 {
+		t.Errorf("expected 3 dropped tokens to be decremented, got %d", -decrementHandler.counter)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("expected the 4 waiting tokens at 20/s to take at least 150ms, took %s", elapsed)
+	}
+}
+
+func TestStepRateLimiter_Block(t *testing.T) {
+	start := time.Now()
+	results, decrementHandler := runRateLimiterStep(StepRateLimiterConfig[int]{
+		Rate:      100,
+		Algorithm: LeakyBucket,
+	}, []int{1, 2, 3, 4, 5})
+
+	if len(results) != 5 {
+		t.Errorf("expected all tokens to pass, got %v", results)
+	}
+	if decrementHandler.called {
+		t.Error("did not expect any token to be dropped")
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("expected 5 tokens at 100/s to take at least 40ms, took %s", elapsed)
+	}
+}
+
+func TestStepRateLimiter_Block_Interrupted(t *testing.T) {
+	step := newStepRateLimiter(StepRateLimiterConfig[int]{Rate: 10}).(*stepRateLimiter[int])
+	step.input = make(chan int, 2)
+	step.output = make(chan int, 2)
+	step.decrementTokensCount = (&mockDecrementTokensHandler{}).Handle
+	step.input <- 1
+	step.input <- 2
+
+	// the replica is stopped while the second token waits for its turn.
+	start := time.Now()
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go step.Run(ctx, &wg)
+	<-step.output
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	if len(step.output) != 0 {
+		t.Fatal("expected the interrupted token not to pass before its turn")
+	}
+	if step.retainedTokens() != 1 {
+		t.Fatalf("expected the interrupted token to be retained, got %d", step.retainedTokens())
+	}
+
+	// another replica admits the interrupted token again.
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	wg.Add(1)
+	go step.Run(ctx, &wg)
+	select {
+	case i := <-step.output:
+		if i != 2 {
+			t.Errorf("expected the interrupted token 2 to pass, got %d", i)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the interrupted token")
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("expected the interrupted token to wait for its turn at 10/s, took %s", elapsed)
+	}
+	if step.retainedTokens() != 0 {
+		t.Errorf("expected no retained tokens, got %d", step.retainedTokens())
+	}
+}
+
+func TestStepRateLimiter_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config StepRateLimiterConfig[int]
+	}{
+		{"MissingRate", StepRateLimiterConfig[int]{}},
+		{"UnknownAlgorithm", StepRateLimiterConfig[int]{Rate: 1, Algorithm: 5}},
+		{"MissingDivert", StepRateLimiterConfig[int]{Rate: 1, Excess: RateLimitDivert}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			newStepRateLimiter(tt.config)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package pipelines
 import (
 	"context"
 	"sync"
+	"time"
 )
 
 // StepTerminalProcess is a function that processes the input data and does not return any data.
@@ -22,6 +23,9 @@ type StepTerminalConfig[I any] struct {
 
 	// Process is the function that processes the input data and does not return any data.
 	Process StepTerminalProcess[I]
+
+	// StepOptions holds the options shared by all steps.
+	StepOptions[I]
 }
 
 // stepTerminal is a struct that represents a step in the pipeline that does not return any data.
@@ -34,10 +38,12 @@ func newStepTerminal[I any](config StepTerminalConfig[I]) IStep[I] {
 	if config.Process == nil {
 		panic("process is required")
 	}
-	return &stepTerminal[I]{
+	step := &stepTerminal[I]{
 		stepBase: newBaseStep[I](config.Label, config.Replicas, config.InputChannelSize),
 		process:  config.Process,
 	}
+	step.setOptions(config.StepOptions)
+	return step
 }
 
 func (s *stepTerminal[I]) Run(ctx context.Context, wg *sync.WaitGroup) {
@@ -50,7 +56,12 @@ func (s *stepTerminal[I]) Run(ctx context.Context, wg *sync.WaitGroup) {
 			if !ok {
 				return
 			}
+			start := time.Now()
+			call := s.beginProcess()
 			s.process(i)
+			s.endProcess(call)
+			s.recordTiming(i, start)
+			s.complete(i)
 			s.decrementTokensCount()
 		}
 	}
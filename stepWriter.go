@@ -0,0 +1,226 @@
+package pipelines
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultWriterBufferSize is the size of the buffer of the writer step if it is not set.
+	defaultWriterBufferSize = 64 * 1024
+
+	// defaultWriterFlushInterval is the max time the written tokens wait before they are flushed if it is not set.
+	defaultWriterFlushInterval = time.Second
+)
+
+// StepWriterConfig is a struct that defines the configuration for a writer step.
+// The writer step is a terminal step which encodes the tokens and writes them to a writer, separated by a delimiter.
+// The writes are buffered and flushed in batches, and the tokens are done only once they are flushed.
+// If the encoder implements HeaderEncoder, its header is written before the first record.
+type StepWriterConfig[I any] struct {
+
+	// Label is the name of the step.
+	Label string
+
+	// InputChannelSize is the buffer size for the input channel to the step
+	InputChannelSize uint16
+
+	// Replicas is the number of replicas (go routines) created to run the step. The replicas share the same writer.
+	Replicas uint16
+
+	// Writer is the writer to which the tokens are written.
+	Writer io.Writer
+
+	// Encoder encodes the tokens into the written records.
+	Encoder Encoder[I]
+
+	// Delimiter is written after every record. It is a new line if it is not set.
+	Delimiter []byte
+
+	// BufferSize is the size of the buffer of the writer in bytes. It is 64KiB if it is not set.
+	BufferSize int
+
+	// FlushCount is the number of written tokens after which the buffer is flushed. It is optional.
+	FlushCount int
+
+	// FlushInterval is the max time the written tokens wait before the buffer is flushed. It is 1 second if it is not set.
+	FlushInterval time.Duration
+
+	// StepOptions holds the options shared by all steps.
+	StepOptions[I]
+}
+
+// stepWriter is a struct that represents a terminal step writing the tokens to a writer.
+type stepWriter[I any] struct {
+	stepBase[I]
+	encoder       Encoder[I]
+	delimiter     []byte
+	flushCount    int
+	flushInterval time.Duration
+
+	// mutex guards the writer and the pending tokens shared by the replicas.
+	mutex       sync.Mutex
+	destination io.Writer
+	writer      *bufio.Writer
+
+	// headerWritten is set once the header of the encoder is written, if it implements HeaderEncoder.
+	headerWritten bool
+
+	// pending are the tokens written to the buffer and not flushed yet.
+	pending []I
+}
+
+func newStepWriter[I any](config StepWriterConfig[I]) IStep[I] {
+	if config.Writer == nil {
+		panic("writer is required")
+	}
+	if config.Encoder == nil {
+		panic("encoder is required")
+	}
+	if config.BufferSize < 0 || config.FlushCount < 0 || config.FlushInterval < 0 {
+		panic("buffer size, flush count and flush interval can't be negative")
+	}
+	if config.Delimiter == nil {
+		config.Delimiter = []byte("\n")
+	}
+	if config.BufferSize == 0 {
+		config.BufferSize = defaultWriterBufferSize
+	}
+	if config.FlushInterval == 0 {
+		config.FlushInterval = defaultWriterFlushInterval
+	}
+	step := &stepWriter[I]{
+		stepBase:      newBaseStep[I](config.Label, config.Replicas, config.InputChannelSize),
+		encoder:       config.Encoder,
+		delimiter:     config.Delimiter,
+		flushCount:    config.FlushCount,
+		flushInterval: config.FlushInterval,
+		destination:   config.Writer,
+		writer:        bufio.NewWriterSize(config.Writer, config.BufferSize),
+	}
+	step.setOptions(config.StepOptions)
+	return step
+}
+
+func (s *stepWriter[I]) Run(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.flush()
+			return
+		case i, ok := <-s.input:
+			if !ok {
+				s.flush()
+				return
+			}
+			start := time.Now()
+			call := s.beginProcess()
+			s.write(i)
+			s.endProcess(call)
+			s.recordTiming(i, start)
+		case <-ticker.C:
+			s.flush()
+		}
+	}
+}
+
+// write encodes the token and writes it to the buffer, flushing the buffer if the flush count is reached.
+func (s *stepWriter[I]) write(token I) {
+	record, err := s.encoder.Encode(token)
+	if err != nil {
+		s.reportError(fmt.Errorf("encoding token: %w", err))
+		s.drop(token)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err = s.writeHeader(); err == nil {
+		_, err = s.writer.Write(record)
+	}
+	if err == nil {
+		_, err = s.writer.Write(s.delimiter)
+	}
+	s.pending = append(s.pending, token)
+	if err != nil {
+		s.fail(err)
+		return
+	}
+	if s.flushCount > 0 && len(s.pending) >= s.flushCount {
+		s.flushLocked()
+	}
+}
+
+// writeHeader writes the header of the encoder before the first record. The mutex must be held.
+func (s *stepWriter[I]) writeHeader() error {
+	encoder, ok := s.encoder.(HeaderEncoder)
+	if !ok || s.headerWritten {
+		return nil
+	}
+	header, err := encoder.EncodeHeader()
+	if err != nil {
+		return fmt.Errorf("encoding header: %w", err)
+	}
+	if len(header) > 0 {
+		if _, err = s.writer.Write(header); err == nil {
+			_, err = s.writer.Write(s.delimiter)
+		}
+	}
+	s.headerWritten = err == nil
+	return err
+}
+
+func (s *stepWriter[I]) flush() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.flushLocked()
+}
+
+// flushLocked flushes the buffer and completes the pending tokens. The mutex must be held.
+func (s *stepWriter[I]) flushLocked() {
+	if len(s.pending) == 0 {
+		return
+	}
+	if err := s.writer.Flush(); err != nil {
+		s.fail(err)
+		return
+	}
+	for _, token := range s.pending {
+		s.complete(token)
+		s.decrementTokensCount()
+	}
+	clear(s.pending)
+	s.pending = s.pending[:0]
+}
+
+// fail reports the write error and drops the pending tokens, since it is unknown which of them reached the writer.
+// The buffer is reset since the buffered writer keeps failing after an error. The mutex must be held.
+func (s *stepWriter[I]) fail(err error) {
+	s.reportError(fmt.Errorf("writing tokens: %w", err))
+	s.writer.Reset(s.destination)
+	for _, token := range s.pending {
+		s.drop(token)
+	}
+	clear(s.pending)
+	s.pending = s.pending[:0]
+}
+
+// pendingTokens returns the number of tokens written to the buffer and not flushed yet.
+func (s *stepWriter[I]) pendingTokens() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.pending)
+}
+
+// drop removes the token which failed to be written from the pipeline.
+func (s *stepWriter[I]) drop(token I) {
+	s.release(token, outcomeDropped)
+	s.decrementTokensCount()
+}
@@ -0,0 +1,153 @@
+package pipelines
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+var itoaEncoder = EncoderFunc[int](func(i int) ([]byte, error) {
+	return []byte(strconv.Itoa(i)), nil
+})
+
+// lockedBuffer is a buffer safe to be read while it is written.
+type lockedBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.String()
+}
+
+// failingWriter is a writer failing every write.
+type failingWriter struct{}
+
+var errWriteFailed = errors.New("write failed")
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errWriteFailed
+}
+
+func TestStepWriter_FlushCount(t *testing.T) {
+	out := &lockedBuffer{}
+	builder := &Builder[int]{}
+	writer := builder.NewStep(StepWriterConfig[int]{
+		Label:         "writer",
+		Writer:        out,
+		Encoder:       itoaEncoder,
+		Delimiter:     []byte(";"),
+		FlushCount:    3,
+		FlushInterval: time.Hour,
+	})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, writer)
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+
+	// the tokens are not done till they are flushed.
+	p.FeedMany([]int{1, 2})
+	time.Sleep(20 * time.Millisecond)
+	if out.String() != "" || p.TokensCount() != 2 {
+		t.Errorf("expected 2 unflushed tokens, got %q and tokens count %d", out.String(), p.TokensCount())
+	}
+
+	p.FeedOne(3)
+	p.WaitTillDone()
+	if out.String() != "1;2;3;" {
+		t.Errorf("expected \"1;2;3;\", got %q", out.String())
+	}
+	if p.Output() != nil {
+		t.Errorf("expected no output since the writer step is terminal")
+	}
+}
+
+func TestStepWriter_FlushInterval(t *testing.T) {
+	out := &lockedBuffer{}
+	builder := &Builder[int]{}
+	writer := builder.NewStep(StepWriterConfig[int]{Writer: out, Encoder: itoaEncoder, FlushInterval: 10 * time.Millisecond})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true}, writer)
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+
+	p.FeedMany([]int{1, 2})
+	p.WaitTillDone()
+	if out.String() != "1\n2\n" {
+		t.Errorf("expected \"1\\n2\\n\", got %q", out.String())
+	}
+}
+
+func TestStepWriter_FlushOnTerminate(t *testing.T) {
+	out := &lockedBuffer{}
+	builder := &Builder[int]{}
+	writer := builder.NewStep(StepWriterConfig[int]{Writer: out, Encoder: itoaEncoder, FlushInterval: time.Hour})
+	p := builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10}, writer)
+	p.Init()
+	p.Run(context.Background())
+
+	p.FeedOne(1)
+	time.Sleep(20 * time.Millisecond)
+	p.Terminate()
+	if out.String() != "1\n" {
+		t.Errorf("expected the pending token to be flushed, got %q", out.String())
+	}
+}
+
+func TestStepWriter_Errors(t *testing.T) {
+	var mutex sync.Mutex
+	var reported []PipelineError
+	builder := &Builder[*Envelope[int]]{}
+	writer := builder.NewStep(StepWriterConfig[*Envelope[int]]{
+		Label:      "writer",
+		Writer:     failingWriter{},
+		BufferSize: 1,
+		FlushCount: 1,
+		Encoder: EncoderFunc[*Envelope[int]](func(e *Envelope[int]) ([]byte, error) {
+			if e.Value < 0 {
+				return nil, errors.New("negative value")
+			}
+			return []byte(strconv.Itoa(e.Value)), nil
+		}),
+	})
+	p := builder.NewPipeline(PipelineConfig{
+		DefaultStepInputChannelSize: 10,
+		ErrorHandler: func(err PipelineError) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			reported = append(reported, err)
+		},
+	}, writer)
+	p.Init()
+	p.Run(context.Background())
+	defer p.Terminate()
+
+	for _, value := range []int{-1, 10} {
+		handle, err := p.FeedTracked(NewEnvelope(value))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		outcome, err := handle.Wait(context.Background())
+		if err != nil || outcome.Dropped != 1 {
+			t.Errorf("expected the token %d to be dropped, got %+v and %v", value, outcome, err)
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(reported) != 2 || reported[0].Step != "writer" || !errors.Is(reported[1], errWriteFailed) {
+		t.Errorf("expected the encoding and writing errors to be reported, got %v", reported)
+	}
+}
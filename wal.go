@@ -0,0 +1,407 @@
+package pipelines
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultWALSegmentSize is the size of the segment files of the write-ahead log if it is not set.
+const defaultWALSegmentSize = 4 << 20
+
+// WALConfig is the configuration of the write-ahead log persisting the tokens fed to the pipeline.
+type WALConfig[I any] struct {
+
+	// Dir is the directory of the segment files of the log. It is created if it doesn't exist.
+	Dir string
+
+	// Codec is used to encode the fed tokens and decode them back when they are replayed.
+	Codec Codec[I]
+
+	// SegmentSize is the size after which the log continues in a new segment file. It is set to 4MiB if it is not set.
+	// The segment files are removed once all their tokens are acknowledged.
+	SegmentSize int64
+
+	// Sync flushes every fed token to the disk before it is accepted, so that it survives operating system crashes as well.
+	Sync bool
+}
+
+// validateWALConfig panics if the write-ahead log configuration is not valid.
+func validateWALConfig[I any](config *WALConfig[I]) {
+	if config.Dir == "" {
+		panic("write-ahead log directory is required")
+	}
+	if config.Codec == nil {
+		panic("write-ahead log codec is required")
+	}
+	if config.SegmentSize < 0 {
+		panic("write-ahead log segment size must not be negative")
+	}
+	var token I
+	if _, ok := any(token).(envelope); !ok {
+		panic("write-ahead log requires the pipeline to be in envelope mode")
+	}
+}
+
+// walSegment is a file of logged tokens along with a file of the sequences of the acknowledged ones.
+type walSegment struct {
+
+	// first is the sequence of the first token in the segment, by which the files are named.
+	first uint64
+
+	// log is open while the segment is active, and ack is opened on the first acknowledgement.
+	log *os.File
+	ack *os.File
+
+	// size is the size of the log file.
+	size int64
+
+	// records and acked are the number of the logged and the acknowledged tokens in the segment.
+	records int
+	acked   int
+
+	// active indicates that the tokens are still appended to the segment.
+	active bool
+}
+
+// walEntry is a logged token which is not acknowledged.
+type walEntry[I any] struct {
+	seq   uint64
+	token I
+}
+
+// writeAheadLog persists the fed tokens till all the tokens derived from them leave the pipeline.
+type writeAheadLog[I any] struct {
+	config WALConfig[I]
+
+	// segments is the segments having tokens which are not acknowledged by the sequence of their first token.
+	segments map[uint64]*walSegment
+
+	// unacked is the segment of every token which is not acknowledged by its sequence.
+	unacked map[uint64]*walSegment
+
+	// active is the segment to which the tokens are appended. It is created by the first append.
+	active *walSegment
+
+	// nextSeq is the sequence of the next logged token.
+	nextSeq uint64
+
+	// replay is the tokens to be fed again once the pipeline is run.
+	replay []walEntry[I]
+
+	// mutex protects the log from race conditions.
+	mutex sync.Mutex
+}
+
+func newWriteAheadLog[I any](config WALConfig[I]) *writeAheadLog[I] {
+	if config.SegmentSize == 0 {
+		config.SegmentSize = defaultWALSegmentSize
+	}
+	return &writeAheadLog[I]{
+		config:   config,
+		segments: make(map[uint64]*walSegment),
+		unacked:  make(map[uint64]*walSegment),
+		nextSeq:  1,
+	}
+}
+
+func (w *writeAheadLog[I]) logPath(first uint64) string {
+	return filepath.Join(w.config.Dir, fmt.Sprintf("%020d.wal", first))
+}
+
+func (w *writeAheadLog[I]) ackPath(first uint64) string {
+	return filepath.Join(w.config.Dir, fmt.Sprintf("%020d.ack", first))
+}
+
+// open loads the segments left by the previous runs, and prepares their unacknowledged tokens to be replayed.
+// The segments having all their tokens acknowledged are removed.
+func (w *writeAheadLog[I]) open() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if err := os.MkdirAll(w.config.Dir, 0o755); err != nil {
+		return err
+	}
+	paths, err := filepath.Glob(filepath.Join(w.config.Dir, "*.wal"))
+	if err != nil {
+		return err
+	}
+	var firsts []uint64
+	for _, path := range paths {
+		first, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(path), ".wal"), 10, 64)
+		if err != nil {
+			continue
+		}
+		firsts = append(firsts, first)
+	}
+	sort.Slice(firsts, func(i, j int) bool { return firsts[i] < firsts[j] })
+
+	for _, first := range firsts {
+		records, size, err := readWALRecords(w.logPath(first))
+		if err != nil {
+			return err
+		}
+		acks, err := readWALAcks(w.ackPath(first))
+		if err != nil {
+			return err
+		}
+		segment := &walSegment{first: first, size: size, records: len(records)}
+		w.nextSeq = max(w.nextSeq, first+1)
+		for _, record := range records {
+			w.nextSeq = max(w.nextSeq, record.seq+1)
+			if acks[record.seq] {
+				segment.acked++
+				continue
+			}
+			token, err := w.config.Codec.Decode(record.data)
+			if err != nil {
+				return fmt.Errorf("decoding token %d: %w", record.seq, err)
+			}
+			w.unacked[record.seq] = segment
+			w.replay = append(w.replay, walEntry[I]{seq: record.seq, token: token})
+		}
+		if segment.acked == segment.records {
+			w.remove(segment)
+			continue
+		}
+		w.segments[first] = segment
+	}
+	return nil
+}
+
+// rewind prepares all the unacknowledged tokens to be replayed again, as the tokens in the pipeline are discarded.
+func (w *writeAheadLog[I]) rewind() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	var replay []walEntry[I]
+	for first := range w.segments {
+		records, _, err := readWALRecords(w.logPath(first))
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if _, ok := w.unacked[record.seq]; !ok {
+				continue
+			}
+			token, err := w.config.Codec.Decode(record.data)
+			if err != nil {
+				return fmt.Errorf("decoding token %d: %w", record.seq, err)
+			}
+			replay = append(replay, walEntry[I]{seq: record.seq, token: token})
+		}
+	}
+	sort.Slice(replay, func(i, j int) bool { return replay[i].seq < replay[j].seq })
+	w.replay = replay
+	return nil
+}
+
+// takeReplay returns the tokens to be replayed, and clears them.
+func (w *writeAheadLog[I]) takeReplay() []walEntry[I] {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	replay := w.replay
+	w.replay = nil
+	return replay
+}
+
+// append logs the token and returns its sequence.
+func (w *writeAheadLog[I]) append(token I) (uint64, error) {
+	data, err := w.config.Codec.Encode(token)
+	if err != nil {
+		return 0, fmt.Errorf("encoding token: %w", err)
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.active != nil && w.active.size >= w.config.SegmentSize {
+		w.deactivate()
+	}
+	if w.active == nil {
+		file, err := os.OpenFile(w.logPath(w.nextSeq), os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return 0, err
+		}
+		w.active = &walSegment{first: w.nextSeq, log: file, active: true}
+		w.segments[w.nextSeq] = w.active
+	}
+
+	seq := w.nextSeq
+	record := binary.AppendUvarint(nil, seq)
+	record = append(record, data...)
+	frame := binary.AppendUvarint(nil, uint64(len(record)))
+	frame = append(frame, record...)
+	if _, err := w.active.log.Write(frame); err != nil {
+		// a partially written record can't be followed by other records, so the log continues in a new segment.
+		w.deactivate()
+		return 0, err
+	}
+	if w.config.Sync {
+		if err := w.active.log.Sync(); err != nil {
+			w.deactivate()
+			return 0, err
+		}
+	}
+	w.nextSeq++
+	w.active.size += int64(len(frame))
+	w.active.records++
+	w.unacked[seq] = w.active
+	return seq, nil
+}
+
+// ack marks the token with the given sequence as acknowledged so that it is not replayed.
+func (w *writeAheadLog[I]) ack(seq uint64) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	segment, ok := w.unacked[seq]
+	if !ok {
+		return nil
+	}
+	delete(w.unacked, seq)
+	segment.acked++
+	if !segment.active && segment.acked == segment.records {
+		w.remove(segment)
+		return nil
+	}
+
+	if segment.ack == nil {
+		file, err := os.OpenFile(w.ackPath(segment.first), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		segment.ack = file
+	}
+	if _, err := segment.ack.Write(binary.BigEndian.AppendUint64(nil, seq)); err != nil {
+		return err
+	}
+	if w.config.Sync {
+		return segment.ack.Sync()
+	}
+	return nil
+}
+
+// deactivate stops appending to the active segment, and removes it if all its tokens are acknowledged.
+func (w *writeAheadLog[I]) deactivate() {
+	segment := w.active
+	w.active = nil
+	segment.active = false
+	segment.log.Close()
+	segment.log = nil
+	if segment.acked == segment.records {
+		w.remove(segment)
+	}
+}
+
+// remove deletes the files of the segment.
+func (w *writeAheadLog[I]) remove(segment *walSegment) {
+	if segment.ack != nil {
+		segment.ack.Close()
+		segment.ack = nil
+	}
+	os.Remove(w.logPath(segment.first))
+	os.Remove(w.ackPath(segment.first))
+	delete(w.segments, segment.first)
+}
+
+// close closes the files of the log. They are opened again if more tokens are logged or acknowledged.
+func (w *writeAheadLog[I]) close() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.active != nil {
+		w.deactivate()
+	}
+	for _, segment := range w.segments {
+		if segment.ack != nil {
+			segment.ack.Close()
+			segment.ack = nil
+		}
+	}
+}
+
+// walRecord is a logged token in its encoded form.
+type walRecord struct {
+	seq  uint64
+	data []byte
+}
+
+// readWALRecords reads the records of the segment file and returns them along with the size of the file.
+// A partially written record at the end of the file, which is left by a crash, is ignored.
+func readWALRecords(path string) ([]walRecord, int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	var records []walRecord
+	var offset int64
+	for len(data) > 0 {
+		size, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < size {
+			break
+		}
+		record := data[n : n+int(size)]
+		seq, m := binary.Uvarint(record)
+		if m <= 0 {
+			break
+		}
+		records = append(records, walRecord{seq: seq, data: record[m:]})
+		data = data[n+int(size):]
+		offset += int64(n) + int64(size)
+	}
+	return records, offset, nil
+}
+
+// readWALAcks reads the sequences of the acknowledged tokens of a segment. A missing file means that nothing is acknowledged.
+func readWALAcks(path string) (map[uint64]bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	acks := make(map[uint64]bool)
+	for ; len(data) >= 8; data = data[8:] {
+		acks[binary.BigEndian.Uint64(data)] = true
+	}
+	return acks, nil
+}
+
+// logToken persists the fed token in the write-ahead log if it is enabled, and returns its sequence in the log.
+func (p *pipeline[I]) logToken(token I) (uint64, error) {
+	if p.wal == nil {
+		return 0, nil
+	}
+	seq, err := p.wal.append(token)
+	if err != nil {
+		return 0, fmt.Errorf("writing write-ahead log: %w", err)
+	}
+	return seq, nil
+}
+
+// replayWAL feeds the tokens which were not acknowledged before the pipeline was run, till the context is cancelled.
+// The tokens are already counted in the pipeline tokens count.
+func (p *pipeline[I]) replayWAL(ctx context.Context, wg *sync.WaitGroup, replay []walEntry[I]) {
+	defer wg.Done()
+	for i, entry := range replay {
+		stampEnvelope(entry.token)
+		p.trackLineage(entry.token, entry.seq, nil)
+		select {
+		case p.inlet(0) <- entry.token:
+		case <-ctx.Done():
+			// the remaining tokens are still in the log, and are replayed once the pipeline is reset and run again.
+			for range replay[i:] {
+				p.decrementTokensCount()
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package pipelines
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func walFiles(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return files
+}
+
+func TestPipeline_WAL_AcknowledgeAll(t *testing.T) {
+	dir := t.TempDir()
+	var results []int
+	var mutex sync.Mutex
+	// fragments every value v into v*10 and v*10+1.
+	builder := &Builder[*Envelope[int]]{}
+	fragmenter := builder.NewStep(StepFragmenterConfig[*Envelope[int]]{
+		Label: "fragmenter",
+		Process: func(e *Envelope[int]) []*Envelope[int] {
+			return []*Envelope[int]{NewEnvelope(e.Value * 10), NewEnvelope(e.Value*10 + 1)}
+		},
+	})
+	sink := builder.NewStep(StepTerminalConfig[*Envelope[int]]{
+		Label: "sink",
+		Process: func(e *Envelope[int]) {
+			mutex.Lock()
+			results = append(results, e.Value)
+			mutex.Unlock()
+		},
+	})
+	p := builder.NewPipelineWithWAL(
+		PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true},
+		WALConfig[*Envelope[int]]{Dir: dir, Codec: NewEnvelopeCodec(NewGobCodec[int]()), SegmentSize: 1},
+		fragmenter, sink,
+	)
+	p.Init()
+	p.Run(context.Background())
+	for i := 1; i <= 5; i++ {
+		if err := p.FeedOne(NewEnvelope(i)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	p.WaitTillDone()
+	p.Terminate()
+
+	if len(results) != 10 {
+		t.Errorf("expected 10 results, got %d", len(results))
+	}
+	// every token is written to a new segment which is removed once the token is acknowledged.
+	if files := walFiles(t, dir); len(files) != 0 {
+		t.Errorf("expected the log to be empty, got %v", files)
+	}
+}
+
+func TestPipeline_WAL_ReplayAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	release := make(chan struct{})
+	defer close(release)
+
+	var crashed []int
+	var crashedMutex sync.Mutex
+	// the first pipeline gets stuck on the second fragment of 3, and is abandoned as if the process crashed.
+	builder := &Builder[*Envelope[int]]{}
+	fragmenter := builder.NewStep(StepFragmenterConfig[*Envelope[int]]{
+		Label: "fragmenter",
+		Process: func(e *Envelope[int]) []*Envelope[int] {
+			return []*Envelope[int]{NewEnvelope(e.Value * 10), NewEnvelope(e.Value*10 + 1)}
+		},
+	})
+	sink := builder.NewStep(StepTerminalConfig[*Envelope[int]]{
+		Label: "sink",
+		Process: func(e *Envelope[int]) {
+			if e.Value == 31 {
+				<-release
+			}
+			crashedMutex.Lock()
+			crashed = append(crashed, e.Value)
+			crashedMutex.Unlock()
+		},
+	})
+	p := builder.NewPipelineWithWAL(
+		PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true},
+		WALConfig[*Envelope[int]]{Dir: dir, Codec: NewEnvelopeCodec(NewGobCodec[int]())},
+		fragmenter, sink,
+	)
+	p.Init()
+	p.Run(context.Background())
+	p.FeedMany([]*Envelope[int]{NewEnvelope(1), NewEnvelope(2), NewEnvelope(3), NewEnvelope(4)})
+	deadline := time.Now().Add(time.Second)
+	for p.TokensCount() != 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	var results []int
+	var mutex sync.Mutex
+	builder = &Builder[*Envelope[int]]{}
+	fragmenter = builder.NewStep(StepFragmenterConfig[*Envelope[int]]{
+		Label: "fragmenter",
+		Process: func(e *Envelope[int]) []*Envelope[int] {
+			return []*Envelope[int]{NewEnvelope(e.Value * 10), NewEnvelope(e.Value*10 + 1)}
+		},
+	})
+	sink = builder.NewStep(StepTerminalConfig[*Envelope[int]]{
+		Label: "sink",
+		Process: func(e *Envelope[int]) {
+			mutex.Lock()
+			results = append(results, e.Value)
+			mutex.Unlock()
+		},
+	})
+	restarted := builder.NewPipelineWithWAL(
+		PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true},
+		WALConfig[*Envelope[int]]{Dir: dir, Codec: NewEnvelopeCodec(NewGobCodec[int]())},
+		fragmenter, sink,
+	)
+	if err := restarted.Init(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	restarted.Run(context.Background())
+	restarted.WaitTillDone()
+	restarted.Terminate()
+
+	// 3 is replayed although its first fragment was processed, and 4 is replayed as it was waiting.
+	sort.Ints(results)
+	expected := []int{30, 31, 40, 41}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, results)
+	}
+	for i := range expected {
+		if results[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, results)
+		}
+	}
+}
+
+func TestPipeline_WAL_ReplayOnReset(t *testing.T) {
+	dir := t.TempDir()
+	var results []int
+	var mutex sync.Mutex
+	builder := &Builder[*Envelope[int]]{}
+	fragmenter := builder.NewStep(StepFragmenterConfig[*Envelope[int]]{
+		Label: "fragmenter",
+		Process: func(e *Envelope[int]) []*Envelope[int] {
+			return []*Envelope[int]{NewEnvelope(e.Value * 10), NewEnvelope(e.Value*10 + 1)}
+		},
+	})
+	sink := builder.NewStep(StepTerminalConfig[*Envelope[int]]{
+		Label: "sink",
+		Process: func(e *Envelope[int]) {
+			mutex.Lock()
+			results = append(results, e.Value)
+			mutex.Unlock()
+		},
+	})
+	p := builder.NewPipelineWithWAL(
+		PipelineConfig{DefaultStepInputChannelSize: 10, TrackTokensCount: true},
+		WALConfig[*Envelope[int]]{Dir: dir, Codec: NewEnvelopeCodec(NewGobCodec[int]())},
+		fragmenter, sink,
+	)
+	p.Init()
+
+	// the tokens fed before running are discarded by the reset, and are replayed from the log instead.
+	p.FeedMany([]*Envelope[int]{NewEnvelope(1), NewEnvelope(2)})
+	if err := p.Reset(false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p.Run(context.Background())
+	p.WaitTillDone()
+	p.Terminate()
+
+	sort.Ints(results)
+	expected := []int{10, 11, 20, 21}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, results)
+	}
+	for i := range expected {
+		if results[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, results)
+		}
+	}
+	if files := walFiles(t, dir); len(files) != 0 {
+		t.Errorf("expected the log to be empty, got %v", files)
+	}
+}
+
+func TestWALConfig_Invalid(t *testing.T) {
+	codec := NewEnvelopeCodec(NewGobCodec[int]())
+	tests := map[string]func(){
+		"missing dir": func() {
+			(&Builder[*Envelope[int]]{}).NewPipelineWithWAL(PipelineConfig{DefaultStepInputChannelSize: 1}, WALConfig[*Envelope[int]]{Codec: codec})
+		},
+		"missing codec": func() {
+			(&Builder[*Envelope[int]]{}).NewPipelineWithWAL(PipelineConfig{DefaultStepInputChannelSize: 1}, WALConfig[*Envelope[int]]{Dir: "wal"})
+		},
+		"plain tokens": func() {
+			(&Builder[int]{}).NewPipelineWithWAL(PipelineConfig{DefaultStepInputChannelSize: 1}, WALConfig[int]{Dir: "wal", Codec: NewGobCodec[int]()})
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected panic")
+				}
+			}()
+			test()
+		})
+	}
+}
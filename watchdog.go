@@ -0,0 +1,260 @@
+package pipelines
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WatchdogConfig is the configuration of the watchdog detecting stuck pipelines.
+type WatchdogConfig struct {
+
+	// Interval is the period between the checks of the watchdog.
+	Interval time.Duration
+
+	// ProcessTimeout is the max time a replica can spend in a single process call before being reported as stuck.
+	// The check is disabled if it is not set.
+	ProcessTimeout time.Duration
+
+	// OnStuck is called with the diagnostic once every time the pipeline is detected to be stuck.
+	OnStuck func(WatchdogReport)
+}
+
+// WatchdogReason is the reason the watchdog reported the pipeline as stuck.
+type WatchdogReason string
+
+const (
+	// WatchdogProcessTimeout is reported when a replica spends more than the process timeout in a single process call.
+	WatchdogProcessTimeout WatchdogReason = "process timeout"
+
+	// WatchdogChannelBlocked is reported when no progress is made while the input channel of a step is full.
+	WatchdogChannelBlocked WatchdogReason = "channel blocked"
+
+	// WatchdogTokensLeaked is reported when the tokens count is not changing while no token is queued, processed, or held by a step.
+	// This causes WaitTillDone to block forever.
+	WatchdogTokensLeaked WatchdogReason = "tokens leaked"
+)
+
+// WatchdogReport is the diagnostic reported by the watchdog when the pipeline is stuck.
+type WatchdogReport struct {
+
+	// Time is the time the pipeline was detected to be stuck.
+	Time time.Time
+
+	// Reason is the reason the pipeline is reported as stuck.
+	Reason WatchdogReason
+
+	// Step is the label of the offending step. It is empty for leaked tokens.
+	Step string
+
+	// StepIndex is the index of the offending step. It is -1 for leaked tokens.
+	StepIndex int
+
+	// TokensCount is the number of tokens in the pipeline.
+	TokensCount uint64
+
+	// Description is the topology of the pipeline with the queue depths at the time of the report.
+	Description PipelineDescription
+
+	// Goroutines is the dump of the stacks of all goroutines.
+	Goroutines []byte
+}
+
+// stepActivity tracks the process calls of all the replicas of a step.
+type stepActivity struct {
+
+	// processed is the number of completed process calls.
+	processed atomic.Uint64
+
+	// nextCall is the id of the next process call.
+	nextCall uint64
+
+	// inFlight is the start time of the process calls in progress.
+	inFlight map[uint64]time.Time
+
+	// mutex protects nextCall and inFlight from race conditions.
+	mutex sync.Mutex
+}
+
+func newStepActivity() *stepActivity {
+	return &stepActivity{inFlight: make(map[uint64]time.Time)}
+}
+
+func (a *stepActivity) begin() uint64 {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.nextCall++
+	a.inFlight[a.nextCall] = time.Now()
+	return a.nextCall
+}
+
+func (a *stepActivity) end(call uint64) {
+	a.mutex.Lock()
+	delete(a.inFlight, call)
+	a.mutex.Unlock()
+	a.processed.Add(1)
+}
+
+// oldest returns the start time of the oldest process call in progress and the number of calls in progress.
+func (a *stepActivity) oldest() (time.Time, int) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	var oldest time.Time
+	for _, start := range a.inFlight {
+		if oldest.IsZero() || start.Before(oldest) {
+			oldest = start
+		}
+	}
+	return oldest, len(a.inFlight)
+}
+
+// activityTracker is implemented by the steps which can report their activity to the watchdog.
+type activityTracker interface {
+	trackActivity() *stepActivity
+}
+
+// watchdogProgress is what the watchdog compares between consecutive checks to detect progress.
+type watchdogProgress struct {
+	processed   uint64
+	tokensCount uint64
+	queued      int
+}
+
+// trackActivities enables the activity tracking of the steps. It has to be called before running the replicas.
+func (p *pipeline[I]) trackActivities() []*stepActivity {
+	activities := make([]*stepActivity, len(p.steps))
+	for i, step := range p.steps {
+		if tracker, ok := step.(activityTracker); ok {
+			activities[i] = tracker.trackActivity()
+		}
+	}
+	return activities
+}
+
+// runWatchdog checks the pipeline periodically till the context is cancelled.
+func (p *pipeline[I]) runWatchdog(ctx context.Context, wg *sync.WaitGroup, activities []*stepActivity) {
+	defer wg.Done()
+
+	ticker := time.NewTicker(p.watchdog.Interval)
+	defer ticker.Stop()
+
+	var previous watchdogProgress
+	reported := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// a paused pipeline is not expected to make any progress.
+			if p.State() != StateRunning {
+				previous = watchdogProgress{}
+				reported = false
+				continue
+			}
+			report, progress := p.checkStuck(activities, previous)
+			previous = progress
+			if report == nil {
+				reported = false
+				continue
+			}
+			if !reported {
+				p.reportError(report.Step, fmt.Errorf("watchdog detected %s", report.Reason))
+				if p.watchdog.OnStuck != nil {
+					report.Goroutines = dumpGoroutines()
+					p.watchdog.OnStuck(*report)
+				}
+			}
+			reported = true
+		}
+	}
+}
+
+// checkStuck returns a report if the pipeline is stuck compared to the previous progress.
+func (p *pipeline[I]) checkStuck(activities []*stepActivity, previous watchdogProgress) (*WatchdogReport, watchdogProgress) {
+	now := time.Now()
+	description := p.Describe()
+	progress := watchdogProgress{tokensCount: p.TokensCount()}
+	inFlight := 0
+	buffered := 0
+	timedOutStep := -1
+	for i, activity := range activities {
+		progress.queued += description.Edges[i].Length + description.Steps[i].Spilled
+		buffered += heldTokens(p.steps[i])
+		if activity == nil {
+			continue
+		}
+		progress.processed += activity.processed.Load()
+		oldest, count := activity.oldest()
+		inFlight += count
+		if timedOutStep < 0 && p.watchdog.ProcessTimeout > 0 && count > 0 && now.Sub(oldest) > p.watchdog.ProcessTimeout {
+			timedOutStep = i
+		}
+	}
+
+	if timedOutStep >= 0 {
+		return p.newWatchdogReport(now, WatchdogProcessTimeout, timedOutStep, progress.tokensCount, description), progress
+	}
+
+	if progress != previous {
+		return nil, progress
+	}
+
+	// the most downstream step with a full input channel is the one not consuming its input.
+	for i := len(description.Steps) - 1; i >= 0; i-- {
+		edge := description.Edges[i]
+		if edge.Capacity > 0 && edge.Length == edge.Capacity {
+			return p.newWatchdogReport(now, WatchdogChannelBlocked, i, progress.tokensCount, description), progress
+		}
+	}
+
+	// the tokens waiting for the consumer of the output are not leaked either.
+	// the exit is read first, so a token moving from the exit to the forwarding is counted twice rather than missed.
+	buffered += len(p.exit)
+	buffered += int(p.forwarding.Load())
+	if progress.queued == 0 && inFlight == 0 && progress.tokensCount > uint64(buffered) {
+		return p.newWatchdogReport(now, WatchdogTokensLeaked, -1, progress.tokensCount, description), progress
+	}
+	return nil, progress
+}
+
+// heldTokens returns the number of tokens counted in the pipeline tokens count which are held by the step between the process calls,
+// like the tokens retained by buffers and leaky buckets, and the tokens pending to be flushed by writers.
+func heldTokens[I any](step IStep[I]) int {
+	held := 0
+	if buffered, ok := step.(bufferedStep); ok {
+		held += buffered.retainedTokens()
+	}
+	if writer, ok := step.(*stepWriter[I]); ok {
+		held += writer.pendingTokens()
+	}
+	return held
+}
+
+func (p *pipeline[I]) newWatchdogReport(now time.Time, reason WatchdogReason, index int, tokensCount uint64, description PipelineDescription) *WatchdogReport {
+	report := &WatchdogReport{
+		Time:        now,
+		Reason:      reason,
+		StepIndex:   index,
+		TokensCount: tokensCount,
+		Description: description,
+	}
+	if index >= 0 {
+		report.Step = p.steps[index].GetLabel()
+	}
+	return report
+}
+
+// dumpGoroutines returns the stacks of all goroutines.
+func dumpGoroutines() []byte {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
@@ -0,0 +1,224 @@
+package pipelines
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestStepActivity(t *testing.T) {
+	activity := newStepActivity()
+
+	if _, count := activity.oldest(); count != 0 {
+		t.Errorf("expected no calls in flight, got %d", count)
+	}
+
+	first := activity.begin()
+	time.Sleep(5 * time.Millisecond)
+	second := activity.begin()
+
+	oldest, count := activity.oldest()
+	if count != 2 {
+		t.Errorf("expected 2 calls in flight, got %d", count)
+	}
+	if time.Since(oldest) < 5*time.Millisecond {
+		t.Error("expected the oldest call to be the first one")
+	}
+
+	activity.end(first)
+	activity.end(second)
+	if _, count := activity.oldest(); count != 0 {
+		t.Errorf("expected no calls in flight, got %d", count)
+	}
+	if activity.processed.Load() != 2 {
+		t.Errorf("expected 2 processed calls, got %d", activity.processed.Load())
+	}
+}
+
+func waitForReport(t *testing.T, reports chan WatchdogReport) WatchdogReport {
+	select {
+	case report := <-reports:
+		return report
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the watchdog report")
+		return WatchdogReport{}
+	}
+}
+
+func TestWatchdog_ProcessTimeout(t *testing.T) {
+	release := make(chan struct{})
+	reports := make(chan WatchdogReport, 10)
+
+	builder := &Builder[int]{}
+	slow := builder.NewStep(StepBasicConfig[int]{
+		Label: "slow",
+		Process: func(i int) int {
+			<-release
+			return i
+		},
+	})
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(int) {}})
+	p := builder.NewPipeline(PipelineConfig{
+		DefaultStepInputChannelSize: 10,
+		TrackTokensCount:            true,
+		Watchdog: &WatchdogConfig{
+			Interval:       10 * time.Millisecond,
+			ProcessTimeout: 30 * time.Millisecond,
+			OnStuck:        func(r WatchdogReport) { reports <- r },
+		},
+	}, slow, sink)
+	p.Init()
+	p.Run(context.Background())
+	p.FeedOne(1)
+
+	report := waitForReport(t, reports)
+	if report.Reason != WatchdogProcessTimeout {
+		t.Errorf("expected reason to be process timeout, got %s", report.Reason)
+	}
+	if report.Step != "slow" || report.StepIndex != 0 {
+		t.Errorf("expected offending step to be 'slow', got '%s' at %d", report.Step, report.StepIndex)
+	}
+	if len(report.Goroutines) == 0 {
+		t.Error("expected goroutines dump to be set")
+	}
+	if len(p.RecentErrors()) == 0 {
+		t.Error("expected the report to be recorded as an error")
+	}
+
+	close(release)
+	p.WaitTillDone()
+	p.Terminate()
+}
+
+func TestWatchdog_ChannelBlocked(t *testing.T) {
+	release := make(chan struct{})
+	reports := make(chan WatchdogReport, 10)
+
+	builder := &Builder[int]{}
+	pass := builder.NewStep(StepBasicConfig[int]{Label: "pass", Process: func(i int) int { return i }})
+	sink := builder.NewStep(StepTerminalConfig[int]{
+		Label:            "sink",
+		InputChannelSize: 2,
+		Process:          func(int) { <-release },
+	})
+	p := builder.NewPipeline(PipelineConfig{
+		DefaultStepInputChannelSize: 10,
+		TrackTokensCount:            true,
+		Watchdog: &WatchdogConfig{
+			Interval: 10 * time.Millisecond,
+			OnStuck:  func(r WatchdogReport) { reports <- r },
+		},
+	}, pass, sink)
+	p.Init()
+	p.Run(context.Background())
+	p.FeedMany([]int{1, 2, 3, 4, 5})
+
+	report := waitForReport(t, reports)
+	if report.Reason != WatchdogChannelBlocked {
+		t.Errorf("expected reason to be channel blocked, got %s", report.Reason)
+	}
+	if report.Step != "sink" {
+		t.Errorf("expected offending step to be 'sink', got '%s'", report.Step)
+	}
+	if report.TokensCount != 5 {
+		t.Errorf("expected tokens count to be 5, got %d", report.TokensCount)
+	}
+
+	close(release)
+	p.WaitTillDone()
+	p.Terminate()
+}
+
+func TestWatchdog_TokensLeaked(t *testing.T) {
+	reports := make(chan WatchdogReport, 10)
+
+	builder := &Builder[int]{}
+	sink := builder.NewStep(StepTerminalConfig[int]{Label: "sink", Process: func(int) {}})
+	p := builder.NewPipeline(PipelineConfig{
+		DefaultStepInputChannelSize: 10,
+		TrackTokensCount:            true,
+		Watchdog: &WatchdogConfig{
+			Interval: 10 * time.Millisecond,
+			OnStuck:  func(r WatchdogReport) { reports <- r },
+		},
+	}, builder.NewStep(StepBasicConfig[int]{Process: func(i int) int { return i }}), sink).(*pipeline[int])
+	p.Init()
+	p.Run(context.Background())
+
+	// simulating a token which is never released.
+	p.incrementTokensCount()
+
+	report := waitForReport(t, reports)
+	if report.Reason != WatchdogTokensLeaked {
+		t.Errorf("expected reason to be tokens leaked, got %s", report.Reason)
+	}
+	if report.StepIndex != -1 {
+		t.Errorf("expected no offending step, got %d", report.StepIndex)
+	}
+
+	select {
+	case <-reports:
+		t.Error("expected the stuck pipeline to be reported once")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	p.Terminate()
+}
+
+func TestWatchdog_HeldTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		last func(builder *Builder[int]) IStep[int]
+	}{
+		{"LeakyBucket", func(builder *Builder[int]) IStep[int] {
+			return builder.NewStep(StepRateLimiterConfig[int]{Rate: 0.1, Burst: 4, Algorithm: LeakyBucket, Excess: RateLimitDrop})
+		}},
+		{"Output", func(builder *Builder[int]) IStep[int] {
+			return builder.NewStep(StepBasicConfig[int]{Process: func(i int) int { return i }})
+		}},
+		{"Writer", func(builder *Builder[int]) IStep[int] {
+			return builder.NewStep(StepWriterConfig[int]{Writer: io.Discard, Encoder: NewJSONCodec[int](), FlushInterval: time.Minute})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports := make(chan WatchdogReport, 10)
+
+			builder := &Builder[int]{}
+			p := builder.NewPipeline(PipelineConfig{
+				DefaultStepInputChannelSize: 10,
+				TrackTokensCount:            true,
+				Watchdog: &WatchdogConfig{
+					Interval: 10 * time.Millisecond,
+					OnStuck:  func(r WatchdogReport) { reports <- r },
+				},
+			}, builder.NewStep(StepBasicConfig[int]{Process: func(i int) int { return i }}), tt.last(builder))
+			p.Init()
+			p.Run(context.Background())
+			p.FeedMany([]int{1, 2, 3})
+
+			// the tokens held by the last step are not leaked.
+			select {
+			case report := <-reports:
+				t.Errorf("expected no report, got %s", report.Reason)
+			case <-time.After(100 * time.Millisecond):
+			}
+			if p.TokensCount() == 0 {
+				t.Error("expected the tokens to be held by the last step")
+			}
+
+			p.Terminate()
+		})
+	}
+}
+
+func TestBuilder_InvalidWatchdogConfig(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Expected to panic, got nil")
+		}
+	}()
+	builder := &Builder[int]{}
+	builder.NewPipeline(PipelineConfig{DefaultStepInputChannelSize: 10, Watchdog: &WatchdogConfig{}}, &mockStep[int]{}, &mockStep[int]{})
+}